@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mapClaims 是任意的 claims, 实现了 jwtcore.Claims.
+type mapClaims map[string]any
+
+func (m mapClaims) GetExpirationTime() (*jwt.NumericDate, error) {
+	return jwt.MapClaims(m).GetExpirationTime()
+}
+
+func (m mapClaims) GetIssuedAt() (*jwt.NumericDate, error) {
+	return jwt.MapClaims(m).GetIssuedAt()
+}
+
+func (m mapClaims) GetNotBefore() (*jwt.NumericDate, error) {
+	return jwt.MapClaims(m).GetNotBefore()
+}
+
+func (m mapClaims) GetIssuer() (string, error) {
+	return jwt.MapClaims(m).GetIssuer()
+}
+
+func (m mapClaims) GetSubject() (string, error) {
+	return jwt.MapClaims(m).GetSubject()
+}
+
+func (m mapClaims) GetAudience() (jwt.ClaimStrings, error) {
+	return jwt.MapClaims(m).GetAudience()
+}
+
+func (m *mapClaims) SetIssuer(issuer string) {
+	m.set("iss", issuer, issuer == "")
+}
+
+func (m *mapClaims) SetSubject(subject string) {
+	m.set("sub", subject, subject == "")
+}
+
+func (m *mapClaims) SetAudience(audience jwt.ClaimStrings) {
+	m.set("aud", audience, len(audience) == 0)
+}
+
+func (m *mapClaims) SetExpiresAt(expiresAt *jwt.NumericDate) {
+	m.set("exp", expiresAt, expiresAt == nil)
+}
+
+func (m *mapClaims) SetNotBefore(notBefore *jwt.NumericDate) {
+	m.set("nbf", notBefore, notBefore == nil)
+}
+
+func (m *mapClaims) SetIssuedAt(issuedAt *jwt.NumericDate) {
+	m.set("iat", issuedAt, issuedAt == nil)
+}
+
+func (m *mapClaims) SetID(id string) {
+	m.set("jti", id, id == "")
+}
+
+// set 设置 claim, zero 为 true 时删除 claim.
+func (m *mapClaims) set(name string, value any, zero bool) {
+	if *m == nil {
+		*m = make(mapClaims)
+	}
+	if zero {
+		delete(*m, name)
+		return
+	}
+	(*m)[name] = value
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// timeClaims 是以时间戳表示的 claims.
+var timeClaims = []string{"iat", "nbf", "exp", "auth_time"}
+
+func runDecode(e *env, args []string) error {
+	fs := newFlagSet(e, "decode")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	token, err := readToken(e, fs.Args())
+	if err != nil {
+		return err
+	}
+	claims := jwt.MapClaims{}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return err
+	}
+	if err = printJSON(e, "Header", parsed.Header); err != nil {
+		return err
+	}
+	if err = printJSON(e, "Claims", claims); err != nil {
+		return err
+	}
+	printTimes(e, claims)
+	return nil
+}
+
+// printJSON 以缩进的 JSON 格式输出 v.
+func printJSON(e *env, title string, v any) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if title != "" {
+		fmt.Fprintf(e.stdout, "%s:\n", title)
+	}
+	_, err = fmt.Fprintf(e.stdout, "%s\n", b)
+	return err
+}
+
+// printTimes 以可读的格式输出时间戳 claims.
+func printTimes(e *env, claims jwt.MapClaims) {
+	now := e.now()
+	title := false
+	for _, name := range timeClaims {
+		v, ok := claims[name].(float64)
+		if !ok {
+			continue
+		}
+		if !title {
+			fmt.Fprintln(e.stdout, "Times:")
+			title = true
+		}
+		t := time.Unix(int64(v), 0).UTC()
+		fmt.Fprintf(e.stdout, "  %-9s %s (%s)\n", name, t.Format(time.RFC3339), relativeTime(t, now))
+	}
+}
+
+// relativeTime 返回 t 相对于 now 的时间, 例如 "10m0s 前" 或 "10m0s 后".
+func relativeTime(t, now time.Time) string {
+	if d := now.Sub(t).Truncate(time.Second); d >= 0 {
+		return d.String() + " 前"
+	}
+	return t.Sub(now).Truncate(time.Second).String() + " 后"
+}
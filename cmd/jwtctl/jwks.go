@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/udugong/token/jwtcore"
+)
+
+func runJWKS(e *env, args []string) error {
+	fs := newFlagSet(e, "jwks")
+	alg := fs.String("alg", "", "JWK 的 alg")
+	use := fs.String("use", "sig", "JWK 的 use")
+	kid := fs.String("kid", "", "JWK 的 kid, 只能在转换一个密钥时使用; 默认为 RFC 7638 指纹")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("%w: 缺少 PEM 文件", errUsage)
+	}
+	if *kid != "" && fs.NArg() > 1 {
+		return fmt.Errorf("%w: -kid 只能在转换一个密钥时使用", errUsage)
+	}
+
+	set := jwtcore.JSONWebKeySet{Keys: []jwtcore.JSONWebKey{}}
+	for _, path := range fs.Args() {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pub, err := publicKeyFromPEM(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		jwk, err := jwtcore.NewJSONWebKey(pub, *kid, *alg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if jwk.KeyID == "" {
+			if jwk.KeyID, err = jwk.Thumbprint(); err != nil {
+				return err
+			}
+		}
+		jwk.Use = *use
+		set.Keys = append(set.Keys, jwk)
+	}
+	return printJSON(e, "", set)
+}
+
+// publicKeyFromPEM 从 PEM 编码的公钥、私钥或证书中获取公钥.
+func publicKeyFromPEM(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("不是 PEM 编码的密钥")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("不支持的 PEM 类型 %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwtcore.ErrUnsupportedKey
+	}
+	return signer.Public(), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+func runKeygen(e *env, args []string) error {
+	fs := newFlagSet(e, "keygen")
+	typ := fs.String("type", "ec", "密钥类型: hmac, rsa, ec 或 ed25519")
+	bits := fs.Int("bits", 0, "RSA 密钥的位数 (默认 2048) 或 HMAC 密钥的字节数 (默认 32)")
+	curve := fs.String("curve", "P-256", "EC 密钥的曲线: P-256, P-384 或 P-521")
+	out := fs.String("out", "", "写入私钥的文件, 公钥写入 <out>.pub; 为空时输出到标准输出")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: 多余的参数 %q", errUsage, fs.Args())
+	}
+
+	var key crypto.Signer
+	var err error
+	switch *typ {
+	case "hmac":
+		return generateHMACKey(e, *bits, *out)
+	case "rsa":
+		if *bits == 0 {
+			*bits = 2048
+		}
+		if *bits < 2048 {
+			return fmt.Errorf("%w: RSA 密钥至少需要 2048 位", errUsage)
+		}
+		key, err = rsa.GenerateKey(rand.Reader, *bits)
+	case "ec":
+		c, ok := map[string]elliptic.Curve{
+			"P-256": elliptic.P256(),
+			"P-384": elliptic.P384(),
+			"P-521": elliptic.P521(),
+		}[*curve]
+		if !ok {
+			return fmt.Errorf("%w: 不支持的曲线 %q", errUsage, *curve)
+		}
+		key, err = ecdsa.GenerateKey(c, rand.Reader)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return fmt.Errorf("%w: 不支持的密钥类型 %q", errUsage, *typ)
+	}
+	if err != nil {
+		return err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	if *out == "" {
+		_, err = fmt.Fprintf(e.stdout, "%s%s", privatePEM, publicPEM)
+		return err
+	}
+	if err = os.WriteFile(*out, privatePEM, 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(*out+".pub", publicPEM, 0o644)
+}
+
+// generateHMACKey 生成 base64url 编码的随机 HMAC 密钥.
+func generateHMACKey(e *env, size int, out string) error {
+	if size == 0 {
+		size = 32
+	}
+	if size < 32 {
+		return fmt.Errorf("%w: HMAC 密钥至少需要 32 字节", errUsage)
+	}
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	if out == "" {
+		_, err := fmt.Fprintln(e.stdout, secret)
+		return err
+	}
+	return os.WriteFile(out, []byte(secret), 0o600)
+}
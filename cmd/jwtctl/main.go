@@ -0,0 +1,172 @@
+// jwtctl 是基于 jwtcore 的命令行工具, 用于签发、解码与校验 jwt, 以及生成密钥与 JWKS.
+//
+// 用法:
+//
+//	jwtctl sign   -alg HS256 -key-file key -expire 10m -claims '{"uid":1}'
+//	jwtctl decode <token>
+//	jwtctl verify -alg RS256 -key-file public.pem <token>
+//	jwtctl keygen -type ec -curve P-256 -out key.pem
+//	jwtctl jwks   -alg RS256 public.pem
+//
+// 没有传入 token 时从标准输入读取.
+// 退出码: 0 成功, 1 失败 (例如 token 无效), 2 参数错误.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/udugong/token/jwtcore"
+)
+
+// 退出码.
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+// env 是命令的运行环境.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	now    func() time.Time
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands = []command{
+	{name: "sign", summary: "签发 token", run: runSign},
+	{name: "decode", summary: "不校验签名, 解码 token 的头部与 claims", run: runDecode},
+	{name: "verify", summary: "校验 token", run: runVerify},
+	{name: "keygen", summary: "生成 HMAC/RSA/EC/Ed25519 密钥", run: runKeygen},
+	{name: "jwks", summary: "将 PEM 编码的密钥转换为 JWKS", run: runJWKS},
+}
+
+// errUsage 表示参数错误, 退出码为 exitUsage.
+var errUsage = errors.New("参数错误")
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		now:    time.Now,
+	}))
+}
+
+// run 执行命令并返回退出码.
+func run(args []string, e *env) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return exitUsage
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(e, args[1:])
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(e.stderr, "jwtctl %s: %v\n", cmd.name, err)
+			return exitUsage
+		default:
+			fmt.Fprintf(e.stderr, "jwtctl %s: %v\n", cmd.name, err)
+			return exitFail
+		}
+	}
+	fmt.Fprintf(e.stderr, "jwtctl: 未知的命令 %q\n", args[0])
+	usage(e.stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "用法: jwtctl <command> [flags]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "使用 jwtctl <command> -h 查看命令的参数.")
+}
+
+// newFlagSet 创建命令的 flag.FlagSet, 解析失败时返回错误而不是退出.
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("jwtctl "+name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	return fs
+}
+
+// parseFlags 解析参数, 将解析错误包装为 errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return nil
+}
+
+// keyFlags 是读取密钥的参数.
+type keyFlags struct {
+	file *string
+	env  *string
+}
+
+func addKeyFlags(fs *flag.FlagSet, desc string) keyFlags {
+	return keyFlags{
+		file: fs.String("key-file", "", desc+"文件"),
+		env:  fs.String("key-env", "", "保存"+desc+"的环境变量"),
+	}
+}
+
+func (k keyFlags) load() (string, error) {
+	if *k.file == "" && *k.env == "" {
+		return "", fmt.Errorf("%w: 需要设置 -key-file 或 -key-env", errUsage)
+	}
+	return jwtcore.KeySource{File: *k.file, Env: *k.env}.Load()
+}
+
+// readToken 从参数或标准输入读取 token.
+func readToken(e *env, args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		b, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return "", err
+		}
+		if token := strings.TrimSpace(string(b)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("%w: 缺少 token", errUsage)
+	case 1:
+		return strings.TrimSpace(args[0]), nil
+	default:
+		return "", fmt.Errorf("%w: 只能传入一个 token", errUsage)
+	}
+}
+
+// signingMethod 返回 alg 对应的签名方式.
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || method == jwt.SigningMethodNone {
+		return nil, fmt.Errorf("%w: 不支持的签名算法 %q", errUsage, alg)
+	}
+	return method, nil
+}
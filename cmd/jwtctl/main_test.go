@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var nowTime = time.Unix(1695571200, 0)
+
+// runCmd 执行命令, 返回退出码、标准输出与标准错误.
+func runCmd(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		now:    func() time.Time { return nowTime },
+	})
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFile 在临时目录中写入文件并返回路径.
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:       "no_command",
+			wantCode:   exitUsage,
+			wantStderr: "用法: jwtctl <command> [flags]",
+		},
+		{
+			name:       "unknown_command",
+			args:       []string{"encode"},
+			wantCode:   exitUsage,
+			wantStderr: `未知的命令 "encode"`,
+		},
+		{
+			name:       "bad_flag",
+			args:       []string{"sign", "-foo"},
+			wantCode:   exitUsage,
+			wantStderr: "flag provided but not defined: -foo",
+		},
+		{
+			name:     "help",
+			args:     []string{"verify", "-h"},
+			wantCode: exitOK,
+		},
+		{
+			name:       "missing_key",
+			args:       []string{"sign"},
+			wantCode:   exitUsage,
+			wantStderr: "需要设置 -key-file 或 -key-env",
+		},
+		{
+			name:       "none_algorithm",
+			args:       []string{"verify", "-alg", "none", "token"},
+			wantCode:   exitUsage,
+			wantStderr: `不支持的签名算法 "none"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCmd("", tt.args...)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Contains(t, stderr, tt.wantStderr)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/udugong/token/jwtcore"
+)
+
+// claimFlags 是可以重复的 -claim key=value 参数.
+type claimFlags []string
+
+func (c *claimFlags) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *claimFlags) Set(v string) error {
+	if !strings.Contains(v, "=") {
+		return fmt.Errorf("格式应为 key=value: %q", v)
+	}
+	*c = append(*c, v)
+	return nil
+}
+
+func runSign(e *env, args []string) error {
+	fs := newFlagSet(e, "sign")
+	alg := fs.String("alg", "HS256", "签名算法")
+	key := addKeyFlags(fs, "签名密钥 (HMAC 密钥或 PEM 编码的私钥)")
+	expire := fs.Duration("expire", 10*time.Minute, "有效期")
+	iss := fs.String("iss", "", "签发人 (iss)")
+	sub := fs.String("sub", "", "主体 (sub)")
+	aud := fs.String("aud", "", "接收方 (aud), 多个时使用逗号分隔")
+	kid := fs.String("kid", "", "头部的 kid")
+	typ := fs.String("typ", "", "头部的 typ")
+	claimsJSON := fs.String("claims", "", "claims JSON, 为 - 时从标准输入读取")
+	var claimArgs claimFlags
+	fs.Var(&claimArgs, "claim", "claim, 格式为 key=value, value 为 JSON 时按 JSON 解析, 可以重复")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: 多余的参数 %q", errUsage, fs.Args())
+	}
+
+	method, err := signingMethod(*alg)
+	if err != nil {
+		return err
+	}
+	signingKey, err := key.load()
+	if err != nil {
+		return err
+	}
+	clm, err := readClaims(e, *claimsJSON, claimArgs)
+	if err != nil {
+		return err
+	}
+
+	opts := []jwtcore.Option[mapClaims, *mapClaims]{
+		jwtcore.WithMethod[mapClaims](method),
+		jwtcore.WithIssuer[mapClaims](*iss),
+		jwtcore.WithTimeFunc[mapClaims](e.now),
+	}
+	if *sub != "" {
+		opts = append(opts, jwtcore.WithGenSubjectFunc[mapClaims](func() string { return *sub }))
+	}
+	if *aud != "" {
+		audience := jwt.ClaimStrings(strings.Split(*aud, ","))
+		opts = append(opts, jwtcore.WithGenAudienceFunc[mapClaims](func() jwt.ClaimStrings {
+			return audience
+		}))
+	}
+	if *kid != "" {
+		opts = append(opts, jwtcore.WithKeyID[mapClaims](*kid))
+	}
+	if *typ != "" {
+		opts = append(opts, jwtcore.WithType[mapClaims](*typ))
+	}
+	token, err := jwtcore.NewTokenManager[mapClaims](signingKey, *expire, opts...).GenerateToken(clm)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(e.stdout, token)
+	return err
+}
+
+// readClaims 从 -claims 与 -claim 参数读取 claims, -claim 会覆盖 -claims 中的同名 claim.
+func readClaims(e *env, claimsJSON string, claimArgs []string) (mapClaims, error) {
+	clm := make(mapClaims)
+	if claimsJSON == "-" {
+		b, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return nil, err
+		}
+		claimsJSON = string(b)
+	}
+	if strings.TrimSpace(claimsJSON) != "" {
+		if err := json.Unmarshal([]byte(claimsJSON), &clm); err != nil {
+			return nil, fmt.Errorf("%w: claims 不是 JSON 对象: %v", errUsage, err)
+		}
+	}
+	for _, arg := range claimArgs {
+		name, value, _ := strings.Cut(arg, "=")
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		clm[name] = v
+	}
+	return clm, nil
+}
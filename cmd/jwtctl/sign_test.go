@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	keyFile := writeFile(t, "key", "sign key")
+	t.Setenv("JWTCTL_TEST_KEY", "sign key")
+
+	code, stdout, stderr := runCmd(`{"uid": 1, "iss": "ignored"}`, "sign",
+		"-key-file", keyFile, "-iss", "issuer", "-sub", "user", "-aud", "api-a,api-b",
+		"-kid", "key-1", "-typ", "at+jwt", "-claims", "-",
+		"-claim", "name=foo", "-claim", "admin=true")
+	require.Equal(t, exitOK, code, stderr)
+	token := strings.TrimSpace(stdout)
+
+	claims := jwt.MapClaims{}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"alg": "HS256", "typ": "at+jwt", "kid": "key-1"}, parsed.Header)
+	assert.Equal(t, jwt.MapClaims{
+		"uid":   float64(1),
+		"name":  "foo",
+		"admin": true,
+		"iss":   "issuer",
+		"sub":   "user",
+		"aud":   []any{"api-a", "api-b"},
+		"iat":   float64(nowTime.Unix()),
+		"exp":   float64(nowTime.Unix() + 600),
+	}, claims)
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "valid",
+			args:       []string{"-key-env", "JWTCTL_TEST_KEY", "-iss", "issuer", "-aud", "api-b", "-typ", "at+jwt"},
+			wantCode:   exitOK,
+			wantStdout: "token 有效\n{\n  \"admin\": true,",
+		},
+		{
+			name:       "quiet",
+			args:       []string{"-key-file", keyFile, "-q"},
+			wantCode:   exitOK,
+			wantStdout: "token 有效\n",
+		},
+		{
+			name:       "bad_key",
+			args:       []string{"-key-file", writeFile(t, "key", "another key")},
+			wantCode:   exitFail,
+			wantStderr: "token 无效: 验证失败: token signature is invalid",
+		},
+		{
+			name:       "issuer_mismatch",
+			args:       []string{"-key-file", keyFile, "-iss", "another issuer"},
+			wantCode:   exitFail,
+			wantStderr: "issuer 不匹配",
+		},
+		{
+			name:       "type_mismatch",
+			args:       []string{"-key-file", keyFile, "-typ", "JWT"},
+			wantCode:   exitFail,
+			wantStderr: "typ 不匹配",
+		},
+		{
+			name:       "algorithm_not_allowed",
+			args:       []string{"-key-file", keyFile, "-alg", "HS512"},
+			wantCode:   exitFail,
+			wantStderr: "签名算法不被允许",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCmd(token, append([]string{"verify"}, tt.args...)...)
+			assert.Equal(t, tt.wantCode, code)
+			assert.True(t, strings.HasPrefix(stdout, tt.wantStdout), stdout)
+			assert.Contains(t, stderr, tt.wantStderr)
+		})
+	}
+}
+
+func TestSign_Asymmetric(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runCmd("", "keygen", "-type", "ed25519", "-out", dir+"/key.pem")
+	require.Equal(t, exitOK, code, stderr)
+	code, stdout, stderr := runCmd("", "sign", "-alg", "EdDSA", "-key-file", dir+"/key.pem",
+		"-claims", `{"uid": 1}`)
+	require.Equal(t, exitOK, code, stderr)
+	code, _, stderr = runCmd("", "verify", "-alg", "EdDSA", "-key-file", dir+"/key.pem.pub",
+		strings.TrimSpace(stdout))
+	assert.Equal(t, exitOK, code, stderr)
+}
+
+func TestSign_BadClaims(t *testing.T) {
+	keyFile := writeFile(t, "key", "sign key")
+	code, _, stderr := runCmd("", "sign", "-key-file", keyFile, "-claims", "[1]")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "claims 不是 JSON 对象")
+	code, _, stderr = runCmd("", "sign", "-key-file", keyFile, "-claim", "uid")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, "格式应为 key=value")
+}
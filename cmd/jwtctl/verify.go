@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/udugong/token/jwtcore"
+)
+
+func runVerify(e *env, args []string) error {
+	fs := newFlagSet(e, "verify")
+	alg := fs.String("alg", "HS256", "签名算法, 只接受该算法签名的 token")
+	key := addKeyFlags(fs, "校验密钥 (HMAC 密钥或 PEM 编码的公钥)")
+	iss := fs.String("iss", "", "要求的签发人 (iss)")
+	aud := fs.String("aud", "", "要求的接收方 (aud), 多个时使用逗号分隔, 满足其一即可")
+	typ := fs.String("typ", "", "要求的头部 typ")
+	leeway := fs.Duration("leeway", 0, "允许的时钟偏差")
+	quiet := fs.Bool("q", false, "校验通过时不输出 claims")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	token, err := readToken(e, fs.Args())
+	if err != nil {
+		return err
+	}
+	method, err := signingMethod(*alg)
+	if err != nil {
+		return err
+	}
+	verifyKey, err := key.load()
+	if err != nil {
+		return err
+	}
+
+	m := jwtcore.NewTokenManager[mapClaims]("", time.Minute,
+		jwtcore.WithMethod[mapClaims](method),
+		jwtcore.WithDecryptKey[mapClaims](verifyKey),
+		jwtcore.WithIssuer[mapClaims](*iss),
+		jwtcore.WithExpectedType[mapClaims](*typ),
+		jwtcore.WithLeeway[mapClaims](*leeway),
+		jwtcore.WithTimeFunc[mapClaims](e.now),
+	)
+	if *aud != "" {
+		m = m.WithOptions(jwtcore.WithAudiences[mapClaims](strings.Split(*aud, ",")...))
+	}
+	clm, err := m.VerifyToken(token)
+	if err != nil {
+		return fmt.Errorf("token 无效: %w", err)
+	}
+	fmt.Fprintln(e.stdout, "token 有效")
+	if *quiet {
+		return nil
+	}
+	return printJSON(e, "", clm)
+}
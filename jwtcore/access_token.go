@@ -0,0 +1,89 @@
+package jwtcore
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessTokenType 是 RFC 9068 JWT access token 的 typ.
+const AccessTokenType = "at+jwt"
+
+// accessTokenRequiredClaims 是 RFC 9068 要求 access token 必须包含的 claims.
+var accessTokenRequiredClaims = []string{"iss", "exp", "aud", "sub", "client_id", "iat", "jti", "scope"}
+
+// AccessTokenClaims 是 RFC 9068 JWT access token 的 claims.
+// See https://datatracker.ietf.org/doc/html/rfc9068#section-2.2
+type AccessTokenClaims struct {
+	// the `client_id` claim. 请求 access token 的 OAuth 2.0 客户端.
+	ClientID string `json:"client_id,omitempty"`
+
+	// the `scope` claim. 以空格分隔的授权范围.
+	Scope string `json:"scope,omitempty"`
+
+	// the `auth_time` claim. 终端用户完成认证的时间.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+
+	// the `acr` (Authentication Context Class Reference) claim.
+	ACR string `json:"acr,omitempty"`
+
+	// the `amr` (Authentication Methods References) claim.
+	AMR []string `json:"amr,omitempty"`
+
+	// the `groups` claim. See https://datatracker.ietf.org/doc/html/rfc7643#section-4.1.2
+	Groups []string `json:"groups,omitempty"`
+
+	// the `roles` claim.
+	Roles []string `json:"roles,omitempty"`
+
+	// the `entitlements` claim.
+	Entitlements []string `json:"entitlements,omitempty"`
+
+	RegisteredClaims
+}
+
+// Scopes 返回 scope 中的所有授权范围.
+func (c AccessTokenClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope 判断 scope 是否包含指定的授权范围.
+func (c AccessTokenClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAccessTokenManager 创建签发与校验 RFC 9068 JWT access token 的 jwt 管理器.
+// 签发时设置 typ 为 at+jwt、iss 为 issuer、aud 为 audience, 并生成随机的 jti;
+// 校验时要求 typ 为 at+jwt, iss 与 issuer 一致, aud 包含 audience 之一,
+// 并且包含 RFC 9068 要求的 iss、exp、aud、sub、client_id、iat、jti 与 scope.
+// opts 在预设的选项之后应用, 可以覆盖预设的选项.
+func NewAccessTokenManager[T jwt.Claims, PT Claims[T]](encryptionKey string, expire time.Duration,
+	issuer string, audience []string, opts ...Option[T, PT]) *TokenManager[T, PT] {
+	preset := []Option[T, PT]{
+		WithIssuer[T, PT](issuer),
+		WithGenAudienceFunc[T, PT](func() jwt.ClaimStrings { return audience }),
+		WithAudiences[T, PT](audience...),
+		WithGenIDFunc[T, PT](randomID),
+		WithType[T, PT](AccessTokenType),
+		WithExpectedType[T, PT](AccessTokenType),
+		WithRequiredClaims[T, PT](accessTokenRequiredClaims...),
+	}
+	return NewTokenManager[T, PT](encryptionKey, expire, append(preset, opts...)...)
+}
+
+// randomID 生成随机的 jti.
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand 不可用时无法生成安全的 jti.
+		panic(err)
+	}
+	return encodeBase64(b)
+}
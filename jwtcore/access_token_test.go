@@ -0,0 +1,106 @@
+package jwtcore
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAccessTokenManager(t *testing.T) {
+	m := NewAccessTokenManager[AccessTokenClaims](encryptionKey, defaultExpire,
+		"https://as.example.com", []string{"https://rs.example.com"})
+	defaultAccessClaims := AccessTokenClaims{
+		ClientID:         "client-1",
+		Scope:            "read write",
+		Roles:            []string{"admin"},
+		RegisteredClaims: RegisteredClaims{Subject: "user-1"},
+	}
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr []error
+	}{
+		{
+			name: "normal",
+			token: func() string {
+				token, err := m.GenerateToken(defaultAccessClaims)
+				require.NoError(t, err)
+				return token
+			},
+		},
+		{
+			name: "missing_client_id_and_scope",
+			token: func() string {
+				token, err := m.GenerateToken(AccessTokenClaims{
+					RegisteredClaims: RegisteredClaims{Subject: "user-1"},
+				})
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: []error{ErrClaimMissing},
+		},
+		{
+			// 同一个密钥签发的 ID token 不能作为 access token 使用
+			name: "id_token",
+			token: func() string {
+				token, err := NewTokenManager[AccessTokenClaims](encryptionKey, defaultExpire,
+					WithIssuer[AccessTokenClaims]("https://as.example.com"),
+					WithType[AccessTokenClaims]("JWT"),
+				).GenerateToken(defaultAccessClaims)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: []error{ErrTypeMismatch},
+		},
+		{
+			name: "another_resource_server",
+			token: func() string {
+				token, err := m.WithOptions(
+					WithGenAudienceFunc[AccessTokenClaims](func() jwt.ClaimStrings {
+						return jwt.ClaimStrings{"https://another.example.com"}
+					}),
+				).GenerateToken(defaultAccessClaims)
+				require.NoError(t, err)
+				return token
+			},
+			wantErr: []error{ErrAudienceMismatch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token()
+			got, err := m.VerifyToken(token)
+			if tt.wantErr != nil {
+				for _, want := range tt.wantErr {
+					assert.ErrorIs(t, err, want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &AccessTokenClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, AccessTokenType, parsed.Header["typ"])
+			assert.Equal(t, "client-1", got.ClientID)
+			assert.Equal(t, "user-1", got.Subject)
+			assert.Equal(t, "https://as.example.com", got.Issuer)
+			assert.Equal(t, jwt.ClaimStrings{"https://rs.example.com"}, got.Audience)
+			assert.NotEmpty(t, got.ID)
+			assert.Equal(t, []string{"admin"}, got.Roles)
+		})
+	}
+}
+
+func TestAccessTokenClaims_HasScope(t *testing.T) {
+	clm := AccessTokenClaims{Scope: "read  write"}
+	assert.Equal(t, []string{"read", "write"}, clm.Scopes())
+	assert.True(t, clm.HasScope("write"))
+	assert.False(t, clm.HasScope("admin"))
+	assert.False(t, AccessTokenClaims{}.HasScope(""))
+}
+
+func TestRandomID(t *testing.T) {
+	assert.NotEqual(t, randomID(), randomID())
+	assert.Len(t, randomID(), 22)
+}
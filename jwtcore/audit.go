@@ -0,0 +1,200 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// AuditTag 是标记 claims 字段审计方式的结构体标签.
+	// `audit:"redact"` 的字段在审计日志中记录为 Redacted, `audit:"-"` 的字段不记录.
+	AuditTag = "audit"
+	// Redacted 是审计日志中被隐藏的 claim 的值.
+	Redacted = "[REDACTED]"
+)
+
+// 审计日志的 event 属性.
+const (
+	auditEventIssue   = "issue"
+	auditEventVerify  = "verify"
+	auditEventRevoke  = "revoke"
+	auditEventReload  = "reload"
+	auditEventRouting = "route"
+)
+
+// TokenFingerprint 返回 token 的指纹, 即 SHA-256 摘要前 16 字节的十六进制编码.
+// 用于在日志中关联 token, 而不记录 token 本身.
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// auditIssued 记录签发 token.
+func auditIssued(ctx context.Context, logger *slog.Logger, alg, kid string, clm any, err error) {
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "签发 token 失败",
+			slog.String("event", auditEventIssue),
+			slog.String("alg", alg),
+			slog.String("kid", kid),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if !logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "签发 token",
+		slog.String("event", auditEventIssue),
+		slog.String("alg", alg),
+		slog.String("kid", kid),
+		auditClaims(clm),
+	)
+}
+
+// auditVerified 记录校验 token 的结果, 校验通过时使用 Debug 级别.
+func auditVerified(ctx context.Context, logger *slog.Logger, token, alg, kid string, clm any, err error) {
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelWarn, "token 校验失败",
+			slog.String("event", auditEventVerify),
+			slog.String("fingerprint", TokenFingerprint(token)),
+			slog.String("alg", alg),
+			slog.String("kid", kid),
+			slog.String("category", string(ErrorCategoryOf(err))),
+			slog.String("reason", err.Error()),
+		)
+		return
+	}
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "token 校验通过",
+		slog.String("event", auditEventVerify),
+		slog.String("fingerprint", TokenFingerprint(token)),
+		slog.String("alg", alg),
+		slog.String("kid", kid),
+		auditClaims(clm),
+	)
+}
+
+// auditRequest 记录 token 校验通过后请求校验失败的原因, 例如客户端证书或 DPoP proof 不匹配.
+func (t *TokenManager[T, PT]) auditRequest(ctx context.Context, token string, err error) {
+	if t.auditLogger == nil {
+		return
+	}
+	var info tokenInfo
+	// 与 VerifyToken 失败时相同, alg 与 kid 只用于记录.
+	if t.checkLimits(token) == nil {
+		if unverified, _, perr := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{}); perr == nil {
+			info.setHeader(unverified.Header)
+		}
+	}
+	auditVerified(ctx, t.auditLogger, token, info.alg, info.kid, nil, err)
+}
+
+// auditRevoked 记录被吊销的 token.
+func auditRevoked(ctx context.Context, logger *slog.Logger, token string, clm any) {
+	logger.LogAttrs(ctx, slog.LevelWarn, "token 已被吊销",
+		slog.String("event", auditEventRevoke),
+		slog.String("fingerprint", TokenFingerprint(token)),
+		auditClaims(clm),
+	)
+}
+
+// auditRouted 记录无法选择 jwt 管理器的 token.
+func auditRouted(ctx context.Context, logger *slog.Logger, token string, err error) {
+	logger.LogAttrs(ctx, slog.LevelWarn, "无法选择 jwt 管理器",
+		slog.String("event", auditEventRouting),
+		slog.String("fingerprint", TokenFingerprint(token)),
+		slog.String("category", string(ErrorCategoryOf(err))),
+		slog.String("reason", err.Error()),
+	)
+}
+
+// auditClaims 返回 claims 的日志属性, 根据 AuditTag 隐藏或忽略字段, 零值的字段不记录.
+func auditClaims(clm any) slog.Attr {
+	v := reflect.ValueOf(clm)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return slog.Group("claims")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return slog.Any("claims", clm)
+	}
+	fields := auditFieldsOf(v.Type())
+	attrs := make([]any, 0, len(fields))
+	for _, f := range fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil || !fv.CanInterface() || fv.IsZero() {
+			continue
+		}
+		if f.redact {
+			attrs = append(attrs, slog.String(f.name, Redacted))
+			continue
+		}
+		if d, ok := fv.Interface().(*jwt.NumericDate); ok {
+			attrs = append(attrs, slog.Time(f.name, d.Time))
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.name, fv.Interface()))
+	}
+	return slog.Group("claims", attrs...)
+}
+
+// auditField 是 claims 中需要记录的字段.
+type auditField struct {
+	index  []int
+	name   string
+	redact bool
+}
+
+// auditFieldsCache 缓存每个类型的 auditField, 键为 reflect.Type.
+var auditFieldsCache sync.Map
+
+func auditFieldsOf(typ reflect.Type) []auditField {
+	if fields, ok := auditFieldsCache.Load(typ); ok {
+		return fields.([]auditField)
+	}
+	fields := appendAuditFields(nil, typ, nil)
+	auditFieldsCache.Store(typ, fields)
+	return fields
+}
+
+// appendAuditFields 与 encoding/json 一致, 展开匿名嵌入且没有 json 名称的结构体字段.
+func appendAuditFields(fields []auditField, typ reflect.Type, index []int) []auditField {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		audit := field.Tag.Get(AuditTag)
+		if name == "-" || audit == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		if field.Anonymous && name == "" {
+			fields = appendAuditFields(fields, field.Type, fieldIndex)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, auditField{index: fieldIndex, name: name, redact: audit == "redact"})
+	}
+	return fields
+}
@@ -0,0 +1,381 @@
+package jwtcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AuditClaims 带有审计标签的 claims.
+type AuditClaims struct {
+	Uid      int64  `json:"uid"`
+	Email    string `json:"email,omitempty" audit:"redact"`
+	Password string `json:"password,omitempty" audit:"-"`
+	Internal string `json:"-"`
+	RegisteredClaims
+}
+
+// auditRecorder 将审计日志以 JSON 格式写入缓冲区.
+type auditRecorder struct {
+	buf bytes.Buffer
+}
+
+func newAuditRecorder(level slog.Level) (*auditRecorder, *slog.Logger) {
+	r := &auditRecorder{}
+	return r, slog.New(slog.NewJSONHandler(&r.buf, &slog.HandlerOptions{Level: level}))
+}
+
+// records 返回解码后的日志记录.
+func (r *auditRecorder) records(t *testing.T) []map[string]any {
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(r.buf.Bytes()))
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestTokenManager_AuditLogger(t *testing.T) {
+	rec, logger := newAuditRecorder(slog.LevelDebug)
+	m := NewTokenManager[AuditClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[AuditClaims](func() time.Time { return nowTime }),
+		WithKeyID[AuditClaims]("k1"),
+		WithGenIDFunc[AuditClaims](func() string { return "id-1" }),
+		WithGenSubjectFunc[AuditClaims](func() string { return "user-1" }),
+		WithGenAudienceFunc[AuditClaims](func() jwt.ClaimStrings { return jwt.ClaimStrings{"api"} }),
+		WithAuditLogger[AuditClaims](logger),
+	)
+	clm := AuditClaims{Uid: 1, Email: "a@example.com", Password: "secret", Internal: "internal"}
+	token, err := m.GenerateToken(clm)
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token + "x")
+	require.Error(t, err)
+
+	out := rec.buf.String()
+	assert.NotContains(t, out, token)
+	assert.NotContains(t, out, "a@example.com")
+	assert.NotContains(t, out, "secret")
+	assert.NotContains(t, out, "internal")
+
+	records := rec.records(t)
+	require.Len(t, records, 3)
+
+	issued := records[0]
+	assert.Equal(t, "INFO", issued["level"])
+	assert.Equal(t, "issue", issued["event"])
+	assert.Equal(t, "HS256", issued["alg"])
+	assert.Equal(t, "k1", issued["kid"])
+	claims := issued["claims"].(map[string]any)
+	assert.Equal(t, float64(1), claims["uid"])
+	assert.Equal(t, Redacted, claims["email"])
+	assert.NotContains(t, claims, "password")
+	assert.NotContains(t, claims, "Internal")
+	assert.NotContains(t, claims, "nbf")
+	assert.Equal(t, "user-1", claims["sub"])
+	assert.Equal(t, "id-1", claims["jti"])
+	assert.Equal(t, []any{"api"}, claims["aud"])
+	assert.Equal(t, nowTime.Add(defaultExpire).Format(time.RFC3339Nano), claims["exp"])
+
+	verified := records[1]
+	assert.Equal(t, "DEBUG", verified["level"])
+	assert.Equal(t, "verify", verified["event"])
+	assert.Equal(t, TokenFingerprint(token), verified["fingerprint"])
+	assert.Equal(t, "k1", verified["kid"])
+	assert.Equal(t, Redacted, verified["claims"].(map[string]any)["email"])
+
+	failed := records[2]
+	assert.Equal(t, "WARN", failed["level"])
+	assert.Equal(t, "verify", failed["event"])
+	assert.Equal(t, TokenFingerprint(token+"x"), failed["fingerprint"])
+	assert.Equal(t, "HS256", failed["alg"])
+	assert.Equal(t, string(CategorySignature), failed["category"])
+	assert.Equal(t, err.Error(), failed["reason"])
+	assert.NotContains(t, failed, "claims")
+}
+
+func TestTokenManager_AuditLogger_Level(t *testing.T) {
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	m := defaultManager.WithOptions(WithAuditLogger[MyClaims](logger))
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	// 校验通过的记录为 Debug 级别
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	_, err = m.VerifyToken("malformed")
+	require.Error(t, err)
+
+	records := rec.records(t)
+	require.Len(t, records, 2)
+	assert.Equal(t, "issue", records[0]["event"])
+	assert.Equal(t, string(CategoryMalformed), records[1]["category"])
+	assert.Equal(t, "", records[1]["alg"])
+}
+
+func TestTokenManager_AuditLogger_SignError(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(encryptionKey), 0o600))
+	kms, err := NewFakeKMS(keyFile, jwt.SigningMethodHS256, "kms-1")
+	require.NoError(t, err)
+	kms.SetFailure(ErrKMSUnavailable)
+
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithSigner[MyClaims](kms), WithAuditLogger[MyClaims](logger))
+	_, err = m.GenerateToken(MyClaims{Uid: 1})
+	require.ErrorIs(t, err, ErrKMSUnavailable)
+
+	records := rec.records(t)
+	require.Len(t, records, 1)
+	assert.Equal(t, "ERROR", records[0]["level"])
+	assert.Equal(t, "issue", records[0]["event"])
+	assert.Equal(t, "kms-1", records[0]["kid"])
+	assert.Equal(t, err.Error(), records[0]["error"])
+}
+
+func TestCachingManager_AuditLogger(t *testing.T) {
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	revoked := false
+	m := NewCachingManager[AuditClaims](NewTokenManager[AuditClaims](encryptionKey, defaultExpire),
+		WithCacheRevocationChecker[AuditClaims](RevocationCheckerFunc[AuditClaims](func(AuditClaims) error {
+			if revoked {
+				return ErrTokenRevoked
+			}
+			return nil
+		})),
+		WithCacheAuditLogger[AuditClaims](logger),
+	)
+	token, err := m.GenerateToken(AuditClaims{Uid: 1, Email: "a@example.com"})
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Zero(t, rec.buf.Len())
+
+	// 命中缓存与未命中缓存时都记录吊销
+	revoked = true
+	for i := 0; i < 2; i++ {
+		_, err = m.VerifyToken(token)
+		require.ErrorIs(t, err, ErrTokenRevoked)
+	}
+	assert.NotContains(t, rec.buf.String(), token)
+	records := rec.records(t)
+	require.Len(t, records, 2)
+	for _, record := range records {
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "revoke", record["event"])
+		assert.Equal(t, TokenFingerprint(token), record["fingerprint"])
+		assert.Equal(t, Redacted, record["claims"].(map[string]any)["email"])
+	}
+}
+
+func TestReloadingManager_AuditLogger(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	configFile := filepath.Join(dir, "jwt.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 1"), 0o600))
+	writeReloadConfig(t, configFile, Config{SigningKey: KeySource{File: keyFile}, Expire: Duration(time.Hour)})
+
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	m, err := NewReloadingManager[MyClaims](configFile, nil,
+		WithReloadAuditLogger(logger),
+		WithReloadErrorHandler(func(error) {}),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 2"), 0o600))
+	m.poll()
+	writeReloadConfig(t, configFile, Config{SigningKey: KeySource{File: keyFile}, Algorithm: "none"})
+	m.poll()
+
+	records := rec.records(t)
+	require.Len(t, records, 2)
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "reload", records[0]["event"])
+	assert.Equal(t, configFile, records[0]["path"])
+	assert.Equal(t, "HS256", records[0]["alg"])
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, "reload", records[1]["event"])
+	assert.Contains(t, records[1]["error"], ErrUnsupportedAlgorithm.Error())
+	assert.NotContains(t, rec.buf.String(), "key 2")
+}
+
+func TestRegistry_AuditLogger(t *testing.T) {
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	r := NewRegistry[MyClaims](WithRegistryAuditLogger(logger))
+	r.Register("https://a.example.com", defaultManager)
+	token, err := defaultManager.WithOptions(
+		WithIssuer[MyClaims]("https://b.example.com")).GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = r.VerifyToken(token)
+	require.ErrorIs(t, err, ErrTenantNotFound)
+
+	assert.NotContains(t, rec.buf.String(), token)
+	records := rec.records(t)
+	require.Len(t, records, 1)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "route", records[0]["event"])
+	assert.Equal(t, TokenFingerprint(token), records[0]["fingerprint"])
+	assert.Equal(t, err.Error(), records[0]["reason"])
+}
+
+func TestAuditClaims(t *testing.T) {
+	type Embedded struct {
+		Secret string `json:"secret" audit:"redact"`
+	}
+	type Claims struct {
+		Embedded
+		Named    Embedded `json:"named"`
+		Token    string   `json:"token" audit:"-"`
+		unexport string
+		RegisteredClaims
+	}
+	clm := &Claims{
+		Embedded: Embedded{Secret: "s1"},
+		Named:    Embedded{Secret: "s2"},
+		Token:    "t",
+		unexport: "u",
+		RegisteredClaims: RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(nowTime),
+		},
+	}
+	attr := auditClaims(clm)
+	assert.Equal(t, "claims", attr.Key)
+	got := make(map[string]slog.Value)
+	for _, a := range attr.Value.Group() {
+		got[a.Key] = a.Value
+	}
+	assert.Equal(t, map[string]slog.Value{
+		"secret": slog.StringValue(Redacted),
+		"named":  slog.AnyValue(Embedded{Secret: "s2"}),
+		"sub":    slog.StringValue("user-1"),
+		"exp":    slog.TimeValue(nowTime),
+	}, got)
+
+	assert.Empty(t, auditClaims((*Claims)(nil)).Value.Group())
+	assert.Equal(t, slog.AnyValue(jwt.MapClaims{"sub": "user-1"}),
+		auditClaims(jwt.MapClaims{"sub": "user-1"}).Value)
+}
+
+func TestTokenFingerprint(t *testing.T) {
+	fp := TokenFingerprint("token")
+	assert.Len(t, fp, 32)
+	assert.Equal(t, fp, TokenFingerprint("token"))
+	assert.NotEqual(t, fp, TokenFingerprint("token2"))
+}
+
+func TestSDJWT_AuditLogger(t *testing.T) {
+	rec, logger := newAuditRecorder(slog.LevelDebug)
+	m := NewTokenManager[Credential](encryptionKey, defaultExpire,
+		WithKeyID[Credential]("k1"), WithAuditLogger[Credential](logger))
+	sd := NewSDJWT(m)
+	issued, err := sd.Issue(Credential{Uid: 1, Name: "foo"}, nil)
+	require.NoError(t, err)
+	_, err = sd.Verify(issued)
+	require.NoError(t, err)
+	_, err = sd.VerifyWithKeyBinding(issued, "aud", "nonce")
+	require.ErrorIs(t, err, ErrInvalidSDJWT)
+
+	assert.NotContains(t, rec.buf.String(), issued)
+	records := rec.records(t)
+	require.Len(t, records, 3)
+	assert.Equal(t, "issue", records[0]["event"])
+	assert.Equal(t, "k1", records[0]["kid"])
+	assert.Equal(t, "verify", records[1]["event"])
+	assert.Equal(t, "DEBUG", records[1]["level"])
+	assert.Equal(t, "HS256", records[1]["alg"])
+	assert.Equal(t, TokenFingerprint(issued), records[1]["fingerprint"])
+	assert.Equal(t, "WARN", records[2]["level"])
+	assert.Equal(t, err.Error(), records[2]["reason"])
+}
+
+func TestMTLS_AuditLogger(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", &ca)
+	otherCert := newTestCertificate(t, "other", &ca)
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	mtls := NewMTLS(NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire,
+		WithKeyID[BoundMyClaims]("k1"), WithAuditLogger[BoundMyClaims](logger)))
+	token, err := mtls.GenerateToken(BoundMyClaims{Uid: 1}, clientCert.Leaf)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	_, err = mtls.VerifyRequest(r)
+	require.ErrorIs(t, err, ErrTokenNotBound)
+	// 使用其他客户端证书的请求, 例如被盗用的 token
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert.Leaf}}
+	_, err = mtls.VerifyRequest(r)
+	require.ErrorIs(t, err, ErrTokenNotBound)
+
+	assert.NotContains(t, rec.buf.String(), token)
+	records := rec.records(t)
+	require.Len(t, records, 3) // 签发与两次校验失败
+	for _, record := range records[1:] {
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "verify", record["event"])
+		assert.Equal(t, TokenFingerprint(token), record["fingerprint"])
+		assert.Equal(t, "HS256", record["alg"])
+		assert.Equal(t, "k1", record["kid"])
+		assert.Equal(t, string(CategoryBinding), record["category"])
+	}
+	assert.Equal(t, err.Error(), records[2]["reason"])
+}
+
+func TestDPoP_AuditLogger(t *testing.T) {
+	const htu = "https://api.example.com/resource"
+	rec, logger := newAuditRecorder(slog.LevelInfo)
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[BoundMyClaims](func() time.Time { return nowTime }),
+		WithAuditLogger[BoundMyClaims](logger))
+	d := NewDPoP(m)
+	clientJWK, err := NewJSONWebKey(&ecPrivateKey.PublicKey, "", "")
+	require.NoError(t, err)
+	token, err := d.GenerateToken(BoundMyClaims{Uid: 1}, clientJWK)
+	require.NoError(t, err)
+	clm, err := NewDPoPProofClaims(http.MethodGet, htu, token, nowTime)
+	require.NoError(t, err)
+	proof, err := SignDPoPProof(jwt.SigningMethodES256, ecPrivateKey, clm)
+	require.NoError(t, err)
+
+	request := func(proofs ...string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, htu, nil)
+		r.Header.Set("Authorization", DPoPScheme+" "+token)
+		for _, p := range proofs {
+			r.Header.Add(DPoPHeader, p)
+		}
+		return r
+	}
+	_, err = d.VerifyRequest(request())
+	require.ErrorIs(t, err, ErrInvalidDPoPProof)
+	_, err = d.VerifyRequest(request(proof))
+	require.NoError(t, err)
+	_, err = d.VerifyRequest(request(proof))
+	require.ErrorIs(t, err, ErrReplayDetected)
+
+	assert.NotContains(t, rec.buf.String(), token)
+	records := rec.records(t)
+	require.Len(t, records, 3) // 签发与两次校验失败
+	for _, record := range records[1:] {
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "verify", record["event"])
+		assert.Equal(t, TokenFingerprint(token), record["fingerprint"])
+		assert.Equal(t, string(CategoryBinding), record["category"])
+	}
+	assert.Equal(t, err.Error(), records[2]["reason"])
+}
@@ -0,0 +1,76 @@
+package jwtcore
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Result 是批量校验中单个 token 的结果.
+type Result[T jwt.Claims] struct {
+	Claims T
+	Err    error
+}
+
+// VerifyTokens 并行校验多个 token, 按输入的顺序返回每个 token 的结果.
+// 相同的 token 只校验一次, 并发数由 WithVerifyConcurrency 设置, 默认为 GOMAXPROCS.
+// ctx 结束后, 尚未校验的 token 的错误为 ctx.Err().
+func (t *TokenManager[T, PT]) VerifyTokens(ctx context.Context, tokens []string) []Result[T] {
+	results := make([]Result[T], len(tokens))
+	// first 记录每个 token 第一次出现的位置, unique 为需要校验的位置.
+	first := make(map[string]int, len(tokens))
+	unique := make([]int, 0, len(tokens))
+	for i, token := range tokens {
+		if _, ok := first[token]; !ok {
+			first[token] = i
+			unique = append(unique, i)
+		}
+	}
+
+	workers := t.verifyConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(unique) {
+		workers = len(unique)
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = t.verifyResult(ctx, tokens[i])
+			}
+		}()
+	}
+	for _, i := range unique {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			results[i] = Result[T]{Err: fmt.Errorf("验证失败: %w", ctx.Err())}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, token := range tokens {
+		if j := first[token]; j != i {
+			results[i] = results[j]
+		}
+	}
+	return results
+}
+
+// verifyResult 校验单个 token, ctx 已经结束时不再校验.
+func (t *TokenManager[T, PT]) verifyResult(ctx context.Context, token string) Result[T] {
+	if err := ctx.Err(); err != nil {
+		return Result[T]{Err: fmt.Errorf("验证失败: %w", err)}
+	}
+	clm, err := t.VerifyTokenContext(ctx, token)
+	return Result[T]{Claims: clm, Err: err}
+}
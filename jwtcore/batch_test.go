@@ -0,0 +1,82 @@
+package jwtcore
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenManager_VerifyTokens(t *testing.T) {
+	var calls atomic.Int32
+	m := defaultManager.WithOptions(WithValidators[MyClaims](ValidatorFunc[MyClaims](
+		func(MyClaims) error {
+			calls.Add(1)
+			return nil
+		})))
+	token1, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	token2, err := m.GenerateToken(MyClaims{Uid: 2})
+	require.NoError(t, err)
+
+	results := m.VerifyTokens(context.Background(),
+		[]string{token1, "bad_token", token2, token1, token1, "bad_token"})
+	require.Len(t, results, 6)
+	for i, want := range []int64{1, 0, 2, 1, 1, 0} {
+		assert.Equal(t, want, results[i].Claims.Uid, i)
+		if want == 0 {
+			assert.ErrorIs(t, results[i].Err, jwt.ErrTokenMalformed, i)
+		} else {
+			assert.NoError(t, results[i].Err, i)
+		}
+	}
+	// 相同的 token 只校验一次
+	assert.Equal(t, int32(2), calls.Load())
+
+	assert.Empty(t, m.VerifyTokens(context.Background(), nil))
+}
+
+func TestTokenManager_VerifyTokens_Concurrency(t *testing.T) {
+	var inflight, maxInflight atomic.Int32
+	m := defaultManager.WithOptions(
+		WithVerifyConcurrency[MyClaims](2),
+		WithValidators[MyClaims](ValidatorFunc[MyClaims](func(MyClaims) error {
+			n := inflight.Add(1)
+			defer inflight.Add(-1)
+			for {
+				old := maxInflight.Load()
+				if n <= old || maxInflight.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})),
+	)
+	tokens := make([]string, 20)
+	for i := range tokens {
+		var err error
+		tokens[i], err = m.GenerateToken(MyClaims{Uid: int64(i + 1)})
+		require.NoError(t, err)
+	}
+	for i, r := range m.VerifyTokens(context.Background(), tokens) {
+		require.NoError(t, r.Err)
+		assert.Equal(t, int64(i+1), r.Claims.Uid)
+	}
+	assert.LessOrEqual(t, maxInflight.Load(), int32(2))
+}
+
+func TestTokenManager_VerifyTokens_Canceled(t *testing.T) {
+	token, err := defaultManager.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, r := range defaultManager.VerifyTokens(ctx, []string{token, "bad_token"}) {
+		assert.ErrorIs(t, r.Err, context.Canceled)
+		assert.Equal(t, MyClaims{}, r.Claims)
+	}
+}
@@ -0,0 +1,289 @@
+package jwtcore
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/udugong/token"
+)
+
+// RevocationChecker 检查 token 是否被吊销.
+type RevocationChecker[T any] interface {
+	// CheckRevoked 在 token 被吊销时返回 ErrTokenRevoked, 无法完成检查时返回其他错误.
+	CheckRevoked(clm T) error
+}
+
+// RevocationCheckerFunc 是函数形式的 RevocationChecker.
+type RevocationCheckerFunc[T any] func(clm T) error
+
+func (f RevocationCheckerFunc[T]) CheckRevoked(clm T) error {
+	return f(clm)
+}
+
+// CacheStats 是 CachingManager 的统计信息.
+type CacheStats struct {
+	Hits      uint64 // 命中缓存的次数
+	Misses    uint64 // 未命中缓存的次数
+	Evictions uint64 // 因容量淘汰的条目数
+	Size      int    // 当前的条目数
+}
+
+// CachingManager 缓存校验通过的 token 的 claims, 避免重复校验签名与解码 JSON.
+// 缓存的键为 token 的 SHA-256 摘要, 条目在 exp 与 TTL 中较早的时间过期, 容量满时淘汰最久未使用的条目.
+// 只缓存校验通过的结果, 每次命中缓存时都会重新检查吊销状态.
+// 返回的 claims 与缓存共享切片等引用类型的字段, 调用方不应修改它们.
+// CachingManager 可以安全地在多个 goroutine 中使用.
+type CachingManager[T jwt.Claims] struct {
+	manager token.Manager[T]
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // 最近使用的条目在前
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	cacheConfig[T]
+}
+
+// cacheEntry 是缓存的条目.
+type cacheEntry[T any] struct {
+	key       [sha256.Size]byte
+	claims    T
+	expiresAt time.Time
+}
+
+type cacheConfig[T jwt.Claims] struct {
+	size        int
+	ttl         time.Duration
+	checker     RevocationChecker[T]
+	timeFunc    func() time.Time
+	auditLogger *slog.Logger
+}
+
+// A CacheOption configures a CachingManager.
+type CacheOption[T jwt.Claims] interface {
+	apply(*cacheConfig[T])
+}
+
+// cacheOptionFunc wraps a func, so it satisfies the CacheOption interface.
+type cacheOptionFunc[T jwt.Claims] func(*cacheConfig[T])
+
+func (f cacheOptionFunc[T]) apply(c *cacheConfig[T]) {
+	f(c)
+}
+
+// WithCacheSize 设置缓存的最大条目数, 默认为 1024.
+func WithCacheSize[T jwt.Claims](size int) CacheOption[T] {
+	return cacheOptionFunc[T](func(c *cacheConfig[T]) {
+		c.size = size
+	})
+}
+
+// WithCacheTTL 设置条目的最长缓存时间, 默认为 1 分钟.
+// 吊销检查之外的校验 (例如 WithMaxAge) 在缓存期间不会重新执行.
+func WithCacheTTL[T jwt.Claims](ttl time.Duration) CacheOption[T] {
+	return cacheOptionFunc[T](func(c *cacheConfig[T]) {
+		c.ttl = ttl
+	})
+}
+
+// WithCacheRevocationChecker 设置吊销检查, 校验通过与命中缓存时都会执行.
+func WithCacheRevocationChecker[T jwt.Claims](checker RevocationChecker[T]) CacheOption[T] {
+	return cacheOptionFunc[T](func(c *cacheConfig[T]) {
+		c.checker = checker
+	})
+}
+
+// WithCacheTimeFunc 设置计算条目过期时间的时间函数,
+// 通常与 jwt 管理器的 WithTimeFunc 相同.
+func WithCacheTimeFunc[T jwt.Claims](fn func() time.Time) CacheOption[T] {
+	return cacheOptionFunc[T](func(c *cacheConfig[T]) {
+		c.timeFunc = fn
+	})
+}
+
+// WithCacheAuditLogger 使用 logger 记录被吊销的 token.
+func WithCacheAuditLogger[T jwt.Claims](logger *slog.Logger) CacheOption[T] {
+	return cacheOptionFunc[T](func(c *cacheConfig[T]) {
+		c.auditLogger = logger
+	})
+}
+
+// NewCachingManager 创建缓存 manager 校验结果的 CachingManager.
+// manager 可以是 TokenManager、ReloadingManager 等 token.Manager 的实现.
+func NewCachingManager[T jwt.Claims](manager token.Manager[T],
+	opts ...CacheOption[T]) *CachingManager[T] {
+	m := &CachingManager[T]{
+		manager: manager,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+		cacheConfig: cacheConfig[T]{
+			size:     1024,
+			ttl:      time.Minute,
+			timeFunc: time.Now,
+		},
+	}
+	for _, opt := range opts {
+		opt.apply(&m.cacheConfig)
+	}
+	return m
+}
+
+// GenerateToken 使用被缓存的 jwt 管理器生成 token.
+func (m *CachingManager[T]) GenerateToken(clm T) (string, error) {
+	return m.manager.GenerateToken(clm)
+}
+
+// GenerateTokenContext 使用被缓存的 jwt 管理器生成 token.
+// 被缓存的 jwt 管理器没有实现 token.ContextManager 时忽略 ctx.
+func (m *CachingManager[T]) GenerateTokenContext(ctx context.Context, clm T) (string, error) {
+	if cm, ok := m.manager.(token.ContextManager[T]); ok {
+		return cm.GenerateTokenContext(ctx, clm)
+	}
+	return m.manager.GenerateToken(clm)
+}
+
+// VerifyToken 校验 token 并返回 claims 与 error.
+// 命中缓存时只检查吊销状态, 否则使用被缓存的 jwt 管理器校验, 校验通过后缓存 claims.
+func (m *CachingManager[T]) VerifyToken(tokenString string) (T, error) {
+	return m.VerifyTokenContext(context.Background(), tokenString)
+}
+
+// VerifyTokenContext 与 VerifyToken 相同, 未命中缓存时将 ctx 传递给实现了
+// token.ContextManager 的 jwt 管理器, 命中缓存时不会产生校验的 span.
+func (m *CachingManager[T]) VerifyTokenContext(ctx context.Context, tokenString string) (T, error) {
+	var zeroClm T
+	key := sha256.Sum256([]byte(tokenString))
+	if clm, ok := m.get(key); ok {
+		m.hits.Add(1)
+		if err := m.checkRevoked(ctx, tokenString, clm); err != nil {
+			// 无法完成检查时保留条目, 下次命中时重新检查.
+			if errors.Is(err, ErrTokenRevoked) {
+				m.remove(key)
+			}
+			return zeroClm, err
+		}
+		return clm, nil
+	}
+	m.misses.Add(1)
+	var clm T
+	var err error
+	if cm, ok := m.manager.(token.ContextManager[T]); ok {
+		clm, err = cm.VerifyTokenContext(ctx, tokenString)
+	} else {
+		clm, err = m.manager.VerifyToken(tokenString)
+	}
+	if err != nil {
+		return zeroClm, err
+	}
+	if err = m.checkRevoked(ctx, tokenString, clm); err != nil {
+		return zeroClm, err
+	}
+	m.add(key, clm)
+	return clm, nil
+}
+
+// Remove 从缓存中删除 token.
+func (m *CachingManager[T]) Remove(tokenString string) {
+	m.remove(sha256.Sum256([]byte(tokenString)))
+}
+
+// Purge 清空缓存.
+func (m *CachingManager[T]) Purge() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[[sha256.Size]byte]*list.Element)
+	m.lru.Init()
+}
+
+// Stats 返回缓存的统计信息.
+func (m *CachingManager[T]) Stats() CacheStats {
+	m.mu.Lock()
+	size := m.lru.Len()
+	m.mu.Unlock()
+	return CacheStats{
+		Hits:      m.hits.Load(),
+		Misses:    m.misses.Load(),
+		Evictions: m.evictions.Load(),
+		Size:      size,
+	}
+}
+
+func (m *CachingManager[T]) checkRevoked(ctx context.Context, tokenString string, clm T) error {
+	if m.checker == nil {
+		return nil
+	}
+	err := m.checker.CheckRevoked(clm)
+	if err == nil {
+		return nil
+	}
+	if m.auditLogger != nil && errors.Is(err, ErrTokenRevoked) {
+		auditRevoked(ctx, m.auditLogger, tokenString, clm)
+	}
+	return fmt.Errorf("验证失败: %w", err)
+}
+
+// get 返回未过期的缓存条目, 并将其移动到最近使用的位置.
+func (m *CachingManager[T]) get(key [sha256.Size]byte) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[key]
+	if !ok {
+		var zeroClm T
+		return zeroClm, false
+	}
+	entry := elem.Value.(*cacheEntry[T])
+	if !m.timeFunc().Before(entry.expiresAt) {
+		m.lru.Remove(elem)
+		delete(m.entries, key)
+		var zeroClm T
+		return zeroClm, false
+	}
+	m.lru.MoveToFront(elem)
+	return entry.claims, true
+}
+
+// add 缓存 claims 直到 exp 与 TTL 中较早的时间, 容量满时淘汰最久未使用的条目.
+func (m *CachingManager[T]) add(key [sha256.Size]byte, clm T) {
+	if m.size <= 0 {
+		return
+	}
+	expiresAt := m.timeFunc().Add(m.ttl)
+	if exp, err := clm.GetExpirationTime(); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		elem.Value.(*cacheEntry[T]).expiresAt = expiresAt
+		m.lru.MoveToFront(elem)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(&cacheEntry[T]{key: key, claims: clm, expiresAt: expiresAt})
+	for m.lru.Len() > m.size {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*cacheEntry[T]).key)
+		m.evictions.Add(1)
+	}
+}
+
+func (m *CachingManager[T]) remove(key [sha256.Size]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if elem, ok := m.entries[key]; ok {
+		m.lru.Remove(elem)
+		delete(m.entries, key)
+	}
+}
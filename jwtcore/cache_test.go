@@ -0,0 +1,173 @@
+package jwtcore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token/tokentest"
+)
+
+// countingManager 记录 VerifyToken 与 VerifyTokenContext 的调用次数.
+type countingManager struct {
+	*TokenManager[MyClaims, *MyClaims]
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *countingManager) VerifyToken(token string) (MyClaims, error) {
+	return m.VerifyTokenContext(context.Background(), token)
+}
+
+func (m *countingManager) VerifyTokenContext(ctx context.Context, token string) (MyClaims, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	return m.TokenManager.VerifyTokenContext(ctx, token)
+}
+
+func TestCachingManager_VerifyToken(t *testing.T) {
+	now := nowTime
+	timeFunc := func() time.Time { return now }
+	inner := &countingManager{TokenManager: NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[MyClaims](timeFunc))}
+	m := NewCachingManager[MyClaims](inner,
+		WithCacheTTL[MyClaims](time.Hour),
+		WithCacheTimeFunc[MyClaims](timeFunc),
+	)
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		got, err := m.VerifyToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), got.Uid)
+	}
+	assert.Equal(t, 1, inner.calls)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Size: 1}, m.Stats())
+
+	// 条目在 exp 时过期, 早于 TTL
+	now = now.Add(defaultExpire)
+	_, err = m.VerifyToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	assert.Equal(t, 2, inner.calls)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Size: 0}, m.Stats())
+
+	// 不缓存校验失败的结果
+	_, err = m.VerifyToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	assert.Equal(t, 3, inner.calls)
+}
+
+func TestCachingManager_TTL(t *testing.T) {
+	now := nowTime
+	timeFunc := func() time.Time { return now }
+	inner := &countingManager{TokenManager: NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[MyClaims](timeFunc))}
+	m := NewCachingManager[MyClaims](inner,
+		WithCacheTTL[MyClaims](time.Minute),
+		WithCacheTimeFunc[MyClaims](timeFunc),
+	)
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCachingManager_Eviction(t *testing.T) {
+	inner := &countingManager{TokenManager: NewTokenManager[MyClaims](encryptionKey, defaultExpire)}
+	m := NewCachingManager[MyClaims](inner, WithCacheSize[MyClaims](2))
+	tokens := make([]string, 3)
+	for i := range tokens {
+		var err error
+		tokens[i], err = m.GenerateToken(MyClaims{Uid: int64(i + 1)})
+		require.NoError(t, err)
+	}
+	for _, token := range []string{tokens[0], tokens[1], tokens[0], tokens[2]} {
+		_, err := m.VerifyToken(token)
+		require.NoError(t, err)
+	}
+	// tokens[1] 最久未使用, 被淘汰
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Evictions: 1, Size: 2}, m.Stats())
+	_, err := m.VerifyToken(tokens[0])
+	require.NoError(t, err)
+	_, err = m.VerifyToken(tokens[1])
+	require.NoError(t, err)
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}, m.Stats())
+
+	m.Remove(tokens[1])
+	assert.Equal(t, 1, m.Stats().Size)
+	m.Purge()
+	assert.Equal(t, 0, m.Stats().Size)
+}
+
+func TestCachingManager_Revocation(t *testing.T) {
+	errUnavailable := errors.New("revocation list unavailable")
+	var checkErr error
+	inner := &countingManager{TokenManager: NewTokenManager[MyClaims](encryptionKey, defaultExpire)}
+	m := NewCachingManager[MyClaims](inner,
+		WithCacheRevocationChecker[MyClaims](RevocationCheckerFunc[MyClaims](func(MyClaims) error {
+			return checkErr
+		})),
+	)
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	// 无法完成检查时不缓存
+	checkErr = errUnavailable
+	got, err := m.VerifyToken(token)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, MyClaims{}, got)
+	assert.Equal(t, 0, m.Stats().Size)
+
+	checkErr = nil
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.Stats().Size)
+
+	// 命中缓存时无法完成检查, 保留条目
+	checkErr = errUnavailable
+	_, err = m.VerifyToken(token)
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 1, m.Stats().Size)
+
+	// 命中缓存时 token 被吊销, 删除条目
+	checkErr = ErrTokenRevoked
+	got, err = m.VerifyToken(token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	assert.Equal(t, MyClaims{}, got)
+	assert.Equal(t, 0, m.Stats().Size)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestCachingManager_Conformance(t *testing.T) {
+	tokentest.RunManagerConformance(t, func(t *testing.T) tokentest.Fixture[MyClaims] {
+		now := nowTime
+		timeFunc := func() time.Time { return now }
+		return tokentest.Fixture[MyClaims]{
+			Manager: NewCachingManager[MyClaims](
+				NewTokenManager[MyClaims](encryptionKey, defaultExpire, WithTimeFunc[MyClaims](timeFunc)),
+				WithCacheTTL[MyClaims](time.Hour),
+				WithCacheTimeFunc[MyClaims](timeFunc),
+			),
+			Claims:  func(i int) MyClaims { return MyClaims{Uid: int64(i + 1)} },
+			Equal:   func(want, got MyClaims) bool { return want.Uid == got.Uid },
+			Advance: func(d time.Duration) { now = now.Add(d) },
+			TTL:     defaultExpire,
+
+			ErrExpired:   jwt.ErrTokenExpired,
+			ErrSignature: jwt.ErrTokenSignatureInvalid,
+			ErrMalformed: jwt.ErrTokenMalformed,
+		}
+	})
+}
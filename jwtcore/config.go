@@ -0,0 +1,329 @@
+package jwtcore
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// ID 生成策略.
+const (
+	IDStrategyNone   = "none"   // 不生成 jti
+	IDStrategyRandom = "random" // 128 位随机数的 base64url 编码
+	IDStrategyUUID   = "uuid"   // UUID v4
+)
+
+// Duration 是配置文件中的时间间隔, 使用 time.ParseDuration 的格式, 例如 "10m".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// KeySource 是密钥的来源, Inline、File 与 Env 只能设置其中之一.
+type KeySource struct {
+	Inline string `json:"inline,omitempty" yaml:"inline,omitempty" toml:"inline,omitempty"` // 密钥本身
+	File   string `json:"file,omitempty" yaml:"file,omitempty" toml:"file,omitempty"`       // 密钥文件的路径
+	Env    string `json:"env,omitempty" yaml:"env,omitempty" toml:"env,omitempty"`          // 保存密钥的环境变量
+}
+
+// IsZero 判断是否没有设置密钥.
+func (k KeySource) IsZero() bool {
+	return k == KeySource{}
+}
+
+// Load 读取密钥.
+func (k KeySource) Load() (string, error) {
+	n := 0
+	for _, s := range []string{k.Inline, k.File, k.Env} {
+		if s != "" {
+			n++
+		}
+	}
+	switch {
+	case n == 0:
+		return "", errors.New("没有设置密钥")
+	case n > 1:
+		return "", errors.New("inline、file 与 env 只能设置其中之一")
+	case k.File != "":
+		b, err := os.ReadFile(k.File)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case k.Env != "":
+		v, ok := os.LookupEnv(k.Env)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 不存在", k.Env)
+		}
+		return v, nil
+	default:
+		return k.Inline, nil
+	}
+}
+
+// Config 是 jwt 管理器的配置, 可以从 JSON、YAML、TOML 与环境变量中读取.
+type Config struct {
+	Algorithm         string    `json:"algorithm,omitempty" yaml:"algorithm,omitempty" toml:"algorithm,omitempty"`                            // 签名算法, 默认为 HS256
+	AllowedAlgorithms []string  `json:"allowed_algorithms,omitempty" yaml:"allowed_algorithms,omitempty" toml:"allowed_algorithms,omitempty"` // 额外允许的签名算法
+	SigningKey        KeySource `json:"signing_key,omitempty" yaml:"signing_key,omitempty" toml:"signing_key,omitempty"`                      // 签名密钥, 只校验 token 时可以不设置
+	VerifyKey         KeySource `json:"verify_key,omitempty" yaml:"verify_key,omitempty" toml:"verify_key,omitempty"`                         // 校验密钥, HMAC 默认与签名密钥相同
+	Expire            Duration  `json:"expire,omitempty" yaml:"expire,omitempty" toml:"expire,omitempty"`                                     // 有效期
+	Issuer            string    `json:"issuer,omitempty" yaml:"issuer,omitempty" toml:"issuer,omitempty"`                                     // 签发人
+	AcceptedIssuers   []string  `json:"accepted_issuers,omitempty" yaml:"accepted_issuers,omitempty" toml:"accepted_issuers,omitempty"`       // 额外接受的签发人
+	Audiences         []string  `json:"audiences,omitempty" yaml:"audiences,omitempty" toml:"audiences,omitempty"`                            // 签发与校验的接收方
+	Leeway            Duration  `json:"leeway,omitempty" yaml:"leeway,omitempty" toml:"leeway,omitempty"`                                     // 允许的时钟偏差
+	MaxAge            Duration  `json:"max_age,omitempty" yaml:"max_age,omitempty" toml:"max_age,omitempty"`                                  // 从 iat 开始计算的最大有效期
+	RequiredClaims    []string  `json:"required_claims,omitempty" yaml:"required_claims,omitempty" toml:"required_claims,omitempty"`          // 必需的 claims
+	IDStrategy        string    `json:"id_strategy,omitempty" yaml:"id_strategy,omitempty" toml:"id_strategy,omitempty"`                      // jti 的生成策略, 默认不生成
+}
+
+// LoadConfig 从文件读取配置, 根据扩展名使用 JSON (.json)、YAML (.yaml/.yml) 或 TOML (.toml) 解码.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(b, &cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &cfg)
+	case ".toml":
+		err = toml.Unmarshal(b, &cfg)
+	default:
+		err = fmt.Errorf("不支持的配置文件格式 %q", ext)
+	}
+	return cfg, err
+}
+
+// ApplyEnv 使用以 prefix 开头的环境变量覆盖配置, 例如 prefix 为 "JWT_" 时:
+// JWT_ALGORITHM, JWT_ALLOWED_ALGORITHMS, JWT_SIGNING_KEY, JWT_SIGNING_KEY_FILE, JWT_SIGNING_KEY_ENV,
+// JWT_VERIFY_KEY, JWT_VERIFY_KEY_FILE, JWT_VERIFY_KEY_ENV, JWT_EXPIRE, JWT_ISSUER, JWT_ACCEPTED_ISSUERS,
+// JWT_AUDIENCES, JWT_LEEWAY, JWT_MAX_AGE, JWT_REQUIRED_CLAIMS, JWT_ID_STRATEGY.
+// 列表使用逗号分隔. 设置了任意一个密钥来源的环境变量时, 替换整个密钥来源.
+func (c *Config) ApplyEnv(prefix string) error {
+	lookup := func(name string) (string, bool) {
+		return os.LookupEnv(prefix + name)
+	}
+	var errs []error
+	setString := func(name string, dst *string) {
+		if v, ok := lookup(name); ok {
+			*dst = v
+		}
+	}
+	setList := func(name string, dst *[]string) {
+		if v, ok := lookup(name); ok {
+			*dst = splitList(v)
+		}
+	}
+	setDuration := func(name string, dst *Duration) {
+		if v, ok := lookup(name); ok {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", prefix, name, err))
+			}
+		}
+	}
+	setKey := func(name string, dst *KeySource) {
+		var k KeySource
+		setString(name, &k.Inline)
+		setString(name+"_FILE", &k.File)
+		setString(name+"_ENV", &k.Env)
+		if !k.IsZero() {
+			*dst = k
+		}
+	}
+
+	setString("ALGORITHM", &c.Algorithm)
+	setList("ALLOWED_ALGORITHMS", &c.AllowedAlgorithms)
+	setKey("SIGNING_KEY", &c.SigningKey)
+	setKey("VERIFY_KEY", &c.VerifyKey)
+	setDuration("EXPIRE", &c.Expire)
+	setString("ISSUER", &c.Issuer)
+	setList("ACCEPTED_ISSUERS", &c.AcceptedIssuers)
+	setList("AUDIENCES", &c.Audiences)
+	setDuration("LEEWAY", &c.Leeway)
+	setDuration("MAX_AGE", &c.MaxAge)
+	setList("REQUIRED_CLAIMS", &c.RequiredClaims)
+	setString("ID_STRATEGY", &c.IDStrategy)
+	return errors.Join(errs...)
+}
+
+// Validate 校验配置, 返回的错误包含所有的配置错误.
+func (c Config) Validate() error {
+	_, _, err := c.load()
+	return err
+}
+
+// method 返回配置的签名方式.
+func (c Config) method() (jwt.SigningMethod, error) {
+	alg := c.Algorithm
+	if alg == "" {
+		alg = jwt.SigningMethodHS256.Alg()
+	}
+	return signingMethod(alg)
+}
+
+// load 校验配置并读取签名密钥与校验密钥.
+func (c Config) load() (signingKey, verifyKey string, err error) {
+	var errs []error
+	fieldErr := func(field string, err error) {
+		errs = append(errs, fmt.Errorf("%s: %w", field, err))
+	}
+
+	method, err := c.method()
+	if err != nil {
+		fieldErr("algorithm", err)
+	}
+	for _, alg := range c.AllowedAlgorithms {
+		if _, err = signingMethod(alg); err != nil {
+			fieldErr("allowed_algorithms", err)
+		}
+	}
+
+	if c.SigningKey.IsZero() && c.VerifyKey.IsZero() {
+		fieldErr("signing_key", errors.New("signing_key 与 verify_key 至少需要设置一个"))
+	}
+	if !c.SigningKey.IsZero() {
+		if signingKey, err = c.SigningKey.Load(); err != nil {
+			fieldErr("signing_key", err)
+		} else if method != nil {
+			if _, err = parseSigningKey(method, signingKey); err != nil {
+				fieldErr("signing_key", err)
+			}
+		}
+	}
+	_, hmac := method.(*jwt.SigningMethodHMAC)
+	switch {
+	case !c.VerifyKey.IsZero():
+		if verifyKey, err = c.VerifyKey.Load(); err != nil {
+			fieldErr("verify_key", err)
+		} else if method != nil {
+			if _, err = parseVerifyKey(method, verifyKey); err != nil {
+				fieldErr("verify_key", err)
+			}
+		}
+	case hmac:
+		verifyKey = signingKey
+	case method != nil:
+		fieldErr("verify_key", errors.New("非对称签名算法需要设置公钥"))
+	}
+
+	if c.Expire <= 0 {
+		fieldErr("expire", errors.New("必须大于 0"))
+	}
+	if c.Leeway < 0 {
+		fieldErr("leeway", errors.New("不能小于 0"))
+	}
+	if c.MaxAge < 0 {
+		fieldErr("max_age", errors.New("不能小于 0"))
+	}
+	for _, claim := range c.RequiredClaims {
+		if claim == "" {
+			fieldErr("required_claims", errors.New("claim 不能为空"))
+		}
+	}
+	if _, err = idGenerator(c.IDStrategy); err != nil {
+		fieldErr("id_strategy", err)
+	}
+	if err = errors.Join(errs...); err != nil {
+		return "", "", err
+	}
+	return signingKey, verifyKey, nil
+}
+
+// NewFromConfig 根据配置创建 jwt 管理器, opts 在配置之后应用.
+// 配置无效时返回 Config.Validate 的错误.
+func NewFromConfig[T jwt.Claims, PT Claims[T]](cfg Config,
+	opts ...Option[T, PT]) (*TokenManager[T, PT], error) {
+	signingKey, verifyKey, err := cfg.load()
+	if err != nil {
+		return nil, err
+	}
+	method, _ := cfg.method()
+	genID, _ := idGenerator(cfg.IDStrategy)
+	options := []Option[T, PT]{
+		WithMethod[T, PT](method),
+		WithDecryptKey[T, PT](verifyKey),
+		WithAllowedAlgorithms[T, PT](cfg.AllowedAlgorithms...),
+		WithIssuer[T, PT](cfg.Issuer),
+		WithAcceptedIssuers[T, PT](cfg.AcceptedIssuers...),
+		WithAudiences[T, PT](cfg.Audiences...),
+		WithLeeway[T, PT](time.Duration(cfg.Leeway)),
+		WithMaxAge[T, PT](time.Duration(cfg.MaxAge)),
+		WithRequiredClaims[T, PT](cfg.RequiredClaims...),
+		WithGenIDFunc[T, PT](genID),
+	}
+	if len(cfg.Audiences) > 0 {
+		audiences := jwt.ClaimStrings(cfg.Audiences)
+		options = append(options, WithGenAudienceFunc[T, PT](func() jwt.ClaimStrings {
+			return audiences
+		}))
+	}
+	return NewTokenManager[T, PT](signingKey, time.Duration(cfg.Expire),
+		append(options, opts...)...), nil
+}
+
+// signingMethod 返回 alg 对应的签名方式, 不允许 none.
+func signingMethod(alg string) (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil || alg == jwt.SigningMethodNone.Alg() {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, alg)
+	}
+	return method, nil
+}
+
+// idGenerator 返回 ID 生成策略对应的函数, 不生成 jti 时返回 nil.
+func idGenerator(strategy string) (func() string, error) {
+	switch strategy {
+	case "", IDStrategyNone:
+		return nil, nil
+	case IDStrategyRandom:
+		return randomID, nil
+	case IDStrategyUUID:
+		return randomUUID, nil
+	default:
+		return nil, fmt.Errorf("不支持的 ID 生成策略 %q", strategy)
+	}
+}
+
+// randomUUID 生成随机的 UUID v4 作为 jti.
+func randomUUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// splitList 将逗号分隔的字符串拆分为列表, 忽略空白.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
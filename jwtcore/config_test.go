@@ -0,0 +1,247 @@
+package jwtcore
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	want := Config{
+		Algorithm:      "RS256",
+		SigningKey:     KeySource{File: "private.pem"},
+		VerifyKey:      KeySource{Env: "JWT_PUBLIC_KEY"},
+		Expire:         Duration(10 * time.Minute),
+		Issuer:         "https://as.example.com",
+		Audiences:      []string{"api-a", "api-b"},
+		Leeway:         Duration(5 * time.Second),
+		RequiredClaims: []string{"exp", "jti"},
+		IDStrategy:     IDStrategyUUID,
+	}
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+	"algorithm": "RS256",
+	"signing_key": {"file": "private.pem"},
+	"verify_key": {"env": "JWT_PUBLIC_KEY"},
+	"expire": "10m",
+	"issuer": "https://as.example.com",
+	"audiences": ["api-a", "api-b"],
+	"leeway": "5s",
+	"required_claims": ["exp", "jti"],
+	"id_strategy": "uuid"
+}`,
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `algorithm: RS256
+signing_key:
+  file: private.pem
+verify_key:
+  env: JWT_PUBLIC_KEY
+expire: 10m
+issuer: https://as.example.com
+audiences: [api-a, api-b]
+leeway: 5s
+required_claims: [exp, jti]
+id_strategy: uuid
+`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `algorithm = "RS256"
+expire = "10m"
+issuer = "https://as.example.com"
+audiences = ["api-a", "api-b"]
+leeway = "5s"
+required_claims = ["exp", "jti"]
+id_strategy = "uuid"
+
+[signing_key]
+file = "private.pem"
+
+[verify_key]
+env = "JWT_PUBLIC_KEY"
+`,
+		},
+		{
+			name:    "bad_duration",
+			file:    "config.json",
+			content: `{"expire": "10 minutes"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported_format",
+			file:    "config.ini",
+			content: `algorithm = RS256`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			got, err := LoadConfig(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	t.Setenv("JWT_ALGORITHM", "HS512")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "/etc/jwt/key")
+	t.Setenv("JWT_EXPIRE", "1h")
+	t.Setenv("JWT_AUDIENCES", "api-a, api-b,")
+	t.Setenv("JWT_LEEWAY", "bad")
+	cfg := Config{
+		Algorithm:  "HS256",
+		SigningKey: KeySource{Inline: "secret"},
+		Issuer:     "issuer",
+	}
+	err := cfg.ApplyEnv("JWT_")
+	assert.ErrorContains(t, err, "JWT_LEEWAY")
+	assert.Equal(t, Config{
+		Algorithm:  "HS512",
+		SigningKey: KeySource{File: "/etc/jwt/key"},
+		Expire:     Duration(time.Hour),
+		Issuer:     "issuer",
+		Audiences:  []string{"api-a", "api-b"},
+	}, cfg)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Setenv("JWT_TEST_SECRET", encryptionKey)
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantFields []string
+	}{
+		{
+			name: "hmac",
+			cfg: Config{
+				SigningKey: KeySource{Env: "JWT_TEST_SECRET"},
+				Expire:     Duration(time.Minute),
+			},
+		},
+		{
+			name: "asymmetric",
+			cfg: Config{
+				Algorithm:  "ES256",
+				SigningKey: KeySource{File: writeKeyFile(t, ecPrivateKeyPEM)},
+				VerifyKey:  KeySource{Inline: ecPublicKeyPEM},
+				Expire:     Duration(time.Minute),
+			},
+		},
+		{
+			name: "verify_only",
+			cfg: Config{
+				Algorithm: "EdDSA",
+				VerifyKey: KeySource{Inline: edPublicKeyPEM},
+				Expire:    Duration(time.Minute),
+			},
+		},
+		{
+			name: "every_error",
+			cfg: Config{
+				Algorithm:         "none",
+				AllowedAlgorithms: []string{"HS1"},
+				SigningKey:        KeySource{Inline: "a", Env: "b"},
+				VerifyKey:         KeySource{Env: "JWT_TEST_MISSING"},
+				Leeway:            Duration(-time.Second),
+				MaxAge:            Duration(-time.Second),
+				RequiredClaims:    []string{""},
+				IDStrategy:        "sequence",
+			},
+			wantFields: []string{"algorithm", "allowed_algorithms", "signing_key", "verify_key",
+				"expire", "leeway", "max_age", "required_claims", "id_strategy"},
+		},
+		{
+			name: "missing_keys",
+			cfg: Config{
+				Expire: Duration(time.Minute),
+			},
+			wantFields: []string{"signing_key"},
+		},
+		{
+			name: "asymmetric_without_public_key",
+			cfg: Config{
+				Algorithm:  "RS256",
+				SigningKey: KeySource{Inline: "not a pem"},
+				Expire:     Duration(time.Minute),
+			},
+			wantFields: []string{"signing_key", "verify_key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantFields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			errs := err.(interface{ Unwrap() []error }).Unwrap()
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, regexp.MustCompile(`^\w+`).FindString(e.Error()))
+			}
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	cfg := Config{
+		Algorithm:      "RS256",
+		SigningKey:     KeySource{File: writeKeyFile(t, rsaPrivateKeyPEM)},
+		VerifyKey:      KeySource{Inline: rsaPublicKeyPEM},
+		Expire:         Duration(10 * time.Minute),
+		Issuer:         "https://as.example.com",
+		Audiences:      []string{"api-a"},
+		Leeway:         Duration(5 * time.Second),
+		RequiredClaims: []string{"jti", "aud"},
+		IDStrategy:     IDStrategyUUID,
+	}
+	m, err := NewFromConfig[MyClaims](cfg)
+	require.NoError(t, err)
+	assert.Equal(t, jwt.SigningMethodRS256, m.Method)
+	assert.Equal(t, 10*time.Minute, m.Expire)
+	assert.Equal(t, 5*time.Second, m.leeway)
+
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	got, err := m.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, "https://as.example.com", got.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"api-a"}, got.Audience)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, got.ID)
+
+	_, err = NewFromConfig[MyClaims](Config{})
+	assert.Error(t, err)
+}
+
+func TestDuration_MarshalText(t *testing.T) {
+	text, err := Duration(90 * time.Second).MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "1m30s", string(text))
+}
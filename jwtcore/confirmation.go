@@ -0,0 +1,69 @@
+package jwtcore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Confirmation 是 `cnf` (Confirmation) claim, 用于将 token 绑定到持有者的密钥.
+// See https://datatracker.ietf.org/doc/html/rfc7800
+type Confirmation struct {
+	// the `jkt` (JWK SHA-256 Thumbprint) member. See https://datatracker.ietf.org/doc/html/rfc9449#section-6.1
+	JWKThumbprint string `json:"jkt,omitempty"`
+
+	// the `x5t#S256` (X.509 Certificate SHA-256 Thumbprint) member. See https://datatracker.ietf.org/doc/html/rfc8705#section-3.1
+	X509Thumbprint string `json:"x5t#S256,omitempty"`
+}
+
+// ConfirmationClaims 持有 `cnf` claim.
+// 需要签发绑定密钥的 token 时, 将其嵌入自定义的 claims.
+type ConfirmationClaims struct {
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// BoundClaims 是可以绑定持有者密钥的 claims.
+type BoundClaims[T jwt.Claims] interface {
+	Claims[T]
+
+	GetConfirmation() *Confirmation
+	SetConfirmation(cnf *Confirmation)
+}
+
+func (c ConfirmationClaims) GetConfirmation() *Confirmation {
+	return c.Confirmation
+}
+
+func (c *ConfirmationClaims) SetConfirmation(cnf *Confirmation) {
+	c.Confirmation = cnf
+}
+
+// bindingKey 是 context 中标记由 DPoP 或 MTLS 校验绑定关系的键.
+type bindingKey struct{}
+
+// withBindingCheck 返回标记绑定关系由调用方校验的 context.
+func withBindingCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bindingKey{}, true)
+}
+
+// checkBinding 拒绝作为普通 Bearer token 出示的绑定 token.
+// 绑定了持有者密钥的 token 只能通过 DPoP 或 MTLS 的 VerifyRequest 校验.
+// See https://datatracker.ietf.org/doc/html/rfc9449#section-7.1
+// and https://datatracker.ietf.org/doc/html/rfc8705#section-3
+func checkBinding(ctx context.Context, claims any) error {
+	c, ok := claims.(interface{ GetConfirmation() *Confirmation })
+	if !ok || ctx.Value(bindingKey{}) != nil {
+		return nil
+	}
+	cnf := c.GetConfirmation()
+	switch {
+	case cnf == nil:
+		return nil
+	case cnf.JWKThumbprint != "":
+		return fmt.Errorf("验证失败: %w: 需要附带 DPoP proof", ErrTokenNotBound)
+	case cnf.X509Thumbprint != "":
+		return fmt.Errorf("验证失败: %w: 需要使用客户端证书", ErrTokenNotBound)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package jwtcore
+
+import "context"
+
+// claimsKey 是 claims 在 context 中的键.
+type claimsKey[T any] struct{}
+
+// ContextWithClaims 返回携带 claims 的 context.
+func ContextWithClaims[T any](ctx context.Context, clm T) context.Context {
+	return context.WithValue(ctx, claimsKey[T]{}, clm)
+}
+
+// ClaimsFromContext 从 context 中获取中间件校验通过的 claims.
+func ClaimsFromContext[T any](ctx context.Context) (T, bool) {
+	clm, ok := ctx.Value(claimsKey[T]{}).(T)
+	return clm, ok
+}
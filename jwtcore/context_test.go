@@ -0,0 +1,18 @@
+package jwtcore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimsFromContext(t *testing.T) {
+	ctx := ContextWithClaims(context.Background(), defaultClaims)
+	got, ok := ClaimsFromContext[MyClaims](ctx)
+	assert.True(t, ok)
+	assert.Equal(t, defaultClaims, got)
+
+	_, ok = ClaimsFromContext[IDTokenClaims](ctx)
+	assert.False(t, ok)
+}
@@ -0,0 +1,48 @@
+package jwtcore
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DiscoveryPath 是 OpenID Provider 配置文档的路径.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// ProviderMetadata 是 OpenID Provider 的配置文档.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type ProviderMetadata struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                    string   `json:"token_endpoint,omitempty"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint,omitempty"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	RegistrationEndpoint             string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported                  []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	GrantTypesSupported              []string `json:"grant_types_supported,omitempty"`
+	ACRValuesSupported               []string `json:"acr_values_supported,omitempty"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethods         []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	ClaimsSupported                  []string `json:"claims_supported,omitempty"`
+}
+
+// NewProviderMetadata 创建 OpenID Provider 配置文档.
+// 默认支持 code/id_token 响应类型, public 主体类型, 以及 manager 的签名算法.
+func NewProviderMetadata[T jwt.Claims, PT Claims[T]](manager *TokenManager[T, PT],
+	jwksURI string) ProviderMetadata {
+	return ProviderMetadata{
+		Issuer:                           manager.Issuer,
+		JWKSURI:                          jwksURI,
+		ResponseTypesSupported:           []string{"code", "id_token", "code id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{manager.Method.Alg()},
+	}
+}
+
+// DiscoveryHandler 返回发布 OpenID Provider 配置文档的 http.Handler.
+// 通常挂载在 issuer 下的 DiscoveryPath 路径.
+func DiscoveryHandler(metadata ProviderMetadata) http.Handler {
+	return jsonHandler(metadata)
+}
@@ -0,0 +1,47 @@
+package jwtcore
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProviderMetadata(t *testing.T) {
+	m := NewTokenManager[IDTokenClaims](rsaPrivateKeyPEM, defaultExpire,
+		WithMethod[IDTokenClaims](jwt.SigningMethodRS256),
+		WithIssuer[IDTokenClaims]("https://idp.example.com"),
+	)
+	got := NewProviderMetadata(m, "https://idp.example.com/jwks.json")
+	assert.Equal(t, "https://idp.example.com", got.Issuer)
+	assert.Equal(t, "https://idp.example.com/jwks.json", got.JWKSURI)
+	assert.Equal(t, []string{"RS256"}, got.IDTokenSigningAlgValuesSupported)
+	assert.Equal(t, []string{"public"}, got.SubjectTypesSupported)
+}
+
+func TestDiscoveryHandler(t *testing.T) {
+	metadata := ProviderMetadata{
+		Issuer:                           "https://idp.example.com",
+		JWKSURI:                          "https://idp.example.com/jwks.json",
+		ResponseTypesSupported:           []string{"code"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{"RS256"},
+	}
+	mux := http.NewServeMux()
+	mux.Handle(DiscoveryPath, DiscoveryHandler(metadata))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/.well-known/openid-configuration", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "https://idp.example.com", got["issuer"])
+	assert.Equal(t, "https://idp.example.com/jwks.json", got["jwks_uri"])
+	assert.Equal(t, []any{"RS256"}, got["id_token_signing_alg_values_supported"])
+	assert.NotContains(t, got, "token_endpoint")
+}
@@ -0,0 +1,348 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// DPoPHeader 是携带 DPoP proof 的请求头.
+	DPoPHeader = "DPoP"
+	// DPoPScheme 是 DPoP 绑定的 token 在 Authorization 请求头中使用的认证方案.
+	DPoPScheme = "DPoP"
+	// dpopProofType 是 DPoP proof 头部的 typ.
+	dpopProofType = "dpop+jwt"
+)
+
+// DPoPProofClaims 是 DPoP proof 的 claims.
+// See https://datatracker.ietf.org/doc/html/rfc9449#section-4.2
+type DPoPProofClaims struct {
+	// the `htm` claim. 请求的 HTTP 方法.
+	HTTPMethod string `json:"htm"`
+
+	// the `htu` claim. 请求的 HTTP URI, 不含查询参数与片段.
+	HTTPURI string `json:"htu"`
+
+	// the `ath` claim. access token 的 SHA-256 哈希值.
+	AccessTokenHash string `json:"ath,omitempty"`
+
+	// the `nonce` claim. 服务端提供的 nonce.
+	Nonce string `json:"nonce,omitempty"`
+
+	RegisteredClaims
+}
+
+// NewDPoPProofClaims 创建 DPoP proof 的 claims, 并生成随机的 jti.
+// accessToken 不为空时计算 ath.
+func NewDPoPProofClaims(htm, htu, accessToken string, iat time.Time) (DPoPProofClaims, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return DPoPProofClaims{}, err
+	}
+	clm := DPoPProofClaims{
+		HTTPMethod: htm,
+		HTTPURI:    htu,
+		RegisteredClaims: RegisteredClaims{
+			ID:       encodeBase64(jti),
+			IssuedAt: jwt.NewNumericDate(iat),
+		},
+	}
+	if accessToken != "" {
+		clm.AccessTokenHash = accessTokenHash(accessToken)
+	}
+	return clm, nil
+}
+
+// SignDPoPProof 使用客户端的私钥签发 DPoP proof.
+// 私钥对应的公钥会以 JWK 的形式放入头部.
+func SignDPoPProof(method jwt.SigningMethod, key crypto.Signer, clm DPoPProofClaims) (string, error) {
+	jwk, err := NewJSONWebKey(key.Public(), "", "")
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, clm)
+	token.Header["typ"] = dpopProofType
+	token.Header["jwk"] = jwk
+	return token.SignedString(key)
+}
+
+// DPoP 签发与校验使用 DPoP 绑定密钥的 token.
+// See https://datatracker.ietf.org/doc/html/rfc9449
+type DPoP[T jwt.Claims, PT BoundClaims[T]] struct {
+	manager *TokenManager[T, PT]
+	dpopConfig
+}
+
+type dpopConfig struct {
+	replayCache ReplayCache                  // 记录已使用的 proof jti
+	proofMaxAge time.Duration                // proof 的最长有效时间
+	leeway      time.Duration                // 校验 proof iat 的容差
+	algorithms  []string                     // proof 允许的签名算法
+	requestURL  func(r *http.Request) string // 获取请求的 htu
+}
+
+// A DPoPOption configures a DPoP.
+type DPoPOption interface {
+	apply(*dpopConfig)
+}
+
+// dpopOptionFunc wraps a func, so it satisfies the DPoPOption interface.
+type dpopOptionFunc func(*dpopConfig)
+
+func (f dpopOptionFunc) apply(c *dpopConfig) {
+	f(c)
+}
+
+// WithDPoPReplayCache 设置记录 proof jti 的 ReplayCache.
+func WithDPoPReplayCache(cache ReplayCache) DPoPOption {
+	return dpopOptionFunc(func(c *dpopConfig) {
+		c.replayCache = cache
+	})
+}
+
+// WithDPoPProofMaxAge 设置 proof 的最长有效时间.
+func WithDPoPProofMaxAge(maxAge time.Duration) DPoPOption {
+	return dpopOptionFunc(func(c *dpopConfig) {
+		c.proofMaxAge = maxAge
+	})
+}
+
+// WithDPoPLeeway 设置校验 proof iat 的容差.
+func WithDPoPLeeway(leeway time.Duration) DPoPOption {
+	return dpopOptionFunc(func(c *dpopConfig) {
+		c.leeway = leeway
+	})
+}
+
+// WithDPoPAlgorithms 设置 proof 允许的签名算法.
+func WithDPoPAlgorithms(algs ...string) DPoPOption {
+	return dpopOptionFunc(func(c *dpopConfig) {
+		c.algorithms = algs
+	})
+}
+
+// WithDPoPRequestURL 设置获取请求 htu 的函数.
+// 服务位于反向代理之后时, 需要还原客户端访问的 URL.
+func WithDPoPRequestURL(fn func(r *http.Request) string) DPoPOption {
+	return dpopOptionFunc(func(c *dpopConfig) {
+		c.requestURL = fn
+	})
+}
+
+// NewDPoP 使用 jwt 管理器创建 DPoP.
+// 默认使用 MemoryReplayCache, proof 最长有效时间为 5 分钟, 容差为 5 秒.
+func NewDPoP[T jwt.Claims, PT BoundClaims[T]](manager *TokenManager[T, PT],
+	opts ...DPoPOption) *DPoP[T, PT] {
+	d := &DPoP[T, PT]{
+		manager: manager,
+		dpopConfig: dpopConfig{
+			proofMaxAge: 5 * time.Minute,
+			leeway:      5 * time.Second,
+			algorithms:  asymmetricAlgorithms,
+			requestURL:  requestURL,
+		},
+	}
+	for _, opt := range opts {
+		opt.apply(&d.dpopConfig)
+	}
+	if d.replayCache == nil {
+		d.replayCache = NewMemoryReplayCache(manager.timeFunc)
+	}
+	return d
+}
+
+// GenerateToken 生成绑定客户端公钥的 token.
+// token 的 cnf.jkt 为公钥的 JWK 指纹.
+func (d *DPoP[T, PT]) GenerateToken(clm T, key JSONWebKey) (string, error) {
+	return d.GenerateTokenContext(context.Background(), clm, key)
+}
+
+// GenerateTokenContext 与 GenerateToken 相同, ctx 会传递给 jwt 管理器.
+func (d *DPoP[T, PT]) GenerateTokenContext(ctx context.Context, clm T, key JSONWebKey) (string, error) {
+	jkt, err := key.Thumbprint()
+	if err != nil {
+		return "", err
+	}
+	PT(&clm).SetConfirmation(&Confirmation{JWKThumbprint: jkt})
+	return d.manager.GenerateTokenContext(ctx, clm)
+}
+
+// VerifyProof 校验 DPoP proof, 返回 proof 的 claims 与公钥的 JWK 指纹.
+// accessToken 不为空时校验 ath.
+func (d *DPoP[T, PT]) VerifyProof(proof, htm, htu, accessToken string) (DPoPProofClaims, string, error) {
+	var clm DPoPProofClaims
+	var jwk JSONWebKey
+	_, err := jwt.ParseWithClaims(proof, &clm,
+		func(t *jwt.Token) (interface{}, error) {
+			if typ, _ := t.Header["typ"].(string); typ != dpopProofType {
+				return nil, fmt.Errorf("typ 错误: %q", typ)
+			}
+			raw, _ := json.Marshal(t.Header["jwk"])
+			var header struct {
+				JSONWebKey
+				D string `json:"d"`
+			}
+			if err := json.Unmarshal(raw, &header); err != nil {
+				return nil, err
+			}
+			if header.D != "" {
+				return nil, fmt.Errorf("jwk 不能包含私钥")
+			}
+			jwk = header.JSONWebKey
+			return jwk.PublicKey()
+		},
+		jwt.WithValidMethods(d.algorithms),
+		jwt.WithTimeFunc(d.manager.timeFunc),
+		jwt.WithLeeway(d.leeway),
+	)
+	if err != nil {
+		return DPoPProofClaims{}, "", fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+	if err = d.verifyProofClaims(clm, htm, htu, accessToken); err != nil {
+		return DPoPProofClaims{}, "", fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+	jkt, err := jwk.Thumbprint()
+	if err != nil {
+		return DPoPProofClaims{}, "", fmt.Errorf("%w: %w", ErrInvalidDPoPProof, err)
+	}
+	expiresAt := clm.IssuedAt.Add(d.proofMaxAge + d.leeway)
+	if !d.replayCache.Use(jkt+":"+clm.ID, expiresAt) {
+		return DPoPProofClaims{}, "", fmt.Errorf("%w: %w", ErrInvalidDPoPProof, ErrReplayDetected)
+	}
+	return clm, jkt, nil
+}
+
+func (d *DPoP[T, PT]) verifyProofClaims(clm DPoPProofClaims, htm, htu, accessToken string) error {
+	if clm.ID == "" {
+		return fmt.Errorf("缺少 jti")
+	}
+	if clm.HTTPMethod != htm {
+		return fmt.Errorf("htm 不匹配")
+	}
+	if !equalHTTPURI(clm.HTTPURI, htu) {
+		return fmt.Errorf("htu 不匹配")
+	}
+	if clm.IssuedAt == nil {
+		return fmt.Errorf("缺少 iat")
+	}
+	now := d.manager.timeFunc()
+	if clm.IssuedAt.After(now.Add(d.leeway)) ||
+		clm.IssuedAt.Add(d.proofMaxAge+d.leeway).Before(now) {
+		return fmt.Errorf("iat 超出有效范围")
+	}
+	if accessToken != "" && clm.AccessTokenHash != accessTokenHash(accessToken) {
+		return fmt.Errorf("ath 不匹配")
+	}
+	return nil
+}
+
+// VerifyRequest 校验请求中 DPoP 绑定的 token 与 DPoP proof, 并返回 claims 与 error.
+// jwt 管理器设置了 WithAuditLogger 时, proof 无效、重放等失败也会记录审计日志.
+func (d *DPoP[T, PT]) VerifyRequest(r *http.Request) (T, error) {
+	var zeroClm T
+	token, ok := tokenFromHeader(r, DPoPScheme)
+	if !ok {
+		return zeroClm, ErrTokenNotFound
+	}
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) != 1 {
+		err := fmt.Errorf("%w: 需要一个 DPoP 请求头", ErrInvalidDPoPProof)
+		d.manager.auditRequest(r.Context(), token, err)
+		return zeroClm, err
+	}
+	clm, err := d.manager.VerifyTokenContext(withBindingCheck(r.Context()), token)
+	if err != nil {
+		return zeroClm, err
+	}
+	if err = d.verifyBinding(r, PT(&clm), token, proofs[0]); err != nil {
+		d.manager.auditRequest(r.Context(), token, err)
+		return zeroClm, err
+	}
+	return clm, nil
+}
+
+// verifyBinding 校验 DPoP proof 以及 token 是否绑定了 proof 的公钥.
+func (d *DPoP[T, PT]) verifyBinding(r *http.Request, clm PT, token, proof string) error {
+	cnf := clm.GetConfirmation()
+	if cnf == nil || cnf.JWKThumbprint == "" {
+		return ErrTokenNotBound
+	}
+	_, jkt, err := d.VerifyProof(proof, r.Method, d.requestURL(r), token)
+	if err != nil {
+		return err
+	}
+	if jkt != cnf.JWKThumbprint {
+		return ErrTokenNotBound
+	}
+	return nil
+}
+
+// Middleware 返回校验 DPoP 的中间件.
+// 校验通过的 claims 可以通过 ClaimsFromContext 获取.
+func (d *DPoP[T, PT]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clm, err := d.VerifyRequest(r)
+		if err != nil {
+			code := "invalid_token"
+			if errors.Is(err, ErrInvalidDPoPProof) {
+				code = "invalid_dpop_proof"
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`%s error=%q, algs=%q`,
+				DPoPScheme, code, strings.Join(d.algorithms, " ")))
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), clm)))
+	})
+}
+
+// accessTokenHash 计算 ath.
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return encodeBase64(sum[:])
+}
+
+// requestURL 返回请求的 htu.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// equalHTTPURI 比较 htu, 忽略查询参数与片段, scheme 与 host 不区分大小写.
+func equalHTTPURI(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) &&
+		strings.EqualFold(ua.Host, ub.Host) &&
+		ua.EscapedPath() == ub.EscapedPath()
+}
+
+// tokenFromHeader 从 Authorization 请求头中获取指定认证方案的 token.
+func tokenFromHeader(r *http.Request, scheme string) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	prefix, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(prefix, scheme) || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,299 @@
+package jwtcore
+
+import (
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type BoundMyClaims struct {
+	Uid int64 `json:"uid,omitempty"`
+	ConfirmationClaims
+	RegisteredClaims
+}
+
+func TestJSONWebKey_Thumbprint(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc7638#section-3.1
+	jwk := JSONWebKey{
+		KeyType:   "RSA",
+		N:         "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:         "AQAB",
+		KeyID:     "2011-04-29",
+		Algorithm: "RS256",
+	}
+	got, err := jwk.Thumbprint()
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", got)
+
+	_, err = JSONWebKey{KeyType: "oct"}.Thumbprint()
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
+
+func TestDPoP_VerifyRequest(t *testing.T) {
+	const htu = "https://api.example.com/resource"
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[BoundMyClaims](func() time.Time { return nowTime }),
+		WithAddParserOption[BoundMyClaims](jwt.WithTimeFunc(func() time.Time { return nowTime })),
+	)
+	clientJWK, err := NewJSONWebKey(&ecPrivateKey.PublicKey, "", "")
+	require.NoError(t, err)
+	accessToken, err := NewDPoP(m).GenerateToken(BoundMyClaims{Uid: 1}, clientJWK)
+	require.NoError(t, err)
+	unboundToken, err := m.GenerateToken(BoundMyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	proof := func(key crypto.Signer, method jwt.SigningMethod, htm, htu, token string,
+		iat time.Time) string {
+		clm, err := NewDPoPProofClaims(htm, htu, token, iat)
+		require.NoError(t, err)
+		s, err := SignDPoPProof(method, key, clm)
+		require.NoError(t, err)
+		return s
+	}
+	replayed := proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime)
+
+	tests := []struct {
+		name    string
+		scheme  string
+		token   string
+		proofs  []string
+		wantErr error
+	}{
+		{
+			name:   "normal",
+			scheme: DPoPScheme,
+			token:  accessToken,
+			proofs: []string{replayed},
+		},
+		{
+			name:    "replayed",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{replayed},
+			wantErr: ErrReplayDetected,
+		},
+		{
+			name:    "bearer_scheme",
+			scheme:  "Bearer",
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime)},
+			wantErr: ErrTokenNotFound,
+		},
+		{
+			name:    "missing_proof",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:   "multiple_proofs",
+			scheme: DPoPScheme,
+			token:  accessToken,
+			proofs: []string{
+				proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime),
+				proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime),
+			},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "unbound_token",
+			scheme:  DPoPScheme,
+			token:   unboundToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, unboundToken, nowTime)},
+			wantErr: ErrTokenNotBound,
+		},
+		{
+			name:    "another_key",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(edPrivateKey, jwt.SigningMethodEdDSA, http.MethodGet, htu, accessToken, nowTime)},
+			wantErr: ErrTokenNotBound,
+		},
+		{
+			name:    "bad_htm",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodPost, htu, accessToken, nowTime)},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "bad_htu",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, "https://api.example.com/other", accessToken, nowTime)},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "bad_ath",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, unboundToken, nowTime)},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "stale_proof",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime.Add(-time.Hour))},
+			wantErr: ErrInvalidDPoPProof,
+		},
+		{
+			name:    "future_proof",
+			scheme:  DPoPScheme,
+			token:   accessToken,
+			proofs:  []string{proof(ecPrivateKey, jwt.SigningMethodES256, http.MethodGet, htu, accessToken, nowTime.Add(time.Minute))},
+			wantErr: ErrInvalidDPoPProof,
+		},
+	}
+	d := NewDPoP(m)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, htu+"?q=1", nil)
+			r.Header.Set("Authorization", tt.scheme+" "+tt.token)
+			for _, p := range tt.proofs {
+				r.Header.Add(DPoPHeader, p)
+			}
+			got, err := d.VerifyRequest(r)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestDPoP_VerifyProof_BadHeader(t *testing.T) {
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[BoundMyClaims](func() time.Time { return nowTime }),
+	)
+	clm, err := NewDPoPProofClaims(http.MethodGet, "https://a.example.com", "", nowTime)
+	require.NoError(t, err)
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{
+			name: "bad_typ",
+			token: func() string {
+				s, err := SignDPoPProof(jwt.SigningMethodES256, ecPrivateKey, clm)
+				require.NoError(t, err)
+				token, _ := jwt.Parse(s, nil)
+				token.Header["typ"] = "JWT"
+				s, err = token.SignedString(ecPrivateKey)
+				require.NoError(t, err)
+				return s
+			},
+		},
+		{
+			name: "hmac",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, clm)
+				token.Header["typ"] = dpopProofType
+				s, err := token.SignedString([]byte(encryptionKey))
+				require.NoError(t, err)
+				return s
+			},
+		},
+		{
+			name: "missing_jwk",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodES256, clm)
+				token.Header["typ"] = dpopProofType
+				s, err := token.SignedString(ecPrivateKey)
+				require.NoError(t, err)
+				return s
+			},
+		},
+	}
+	d := NewDPoP(m)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := d.VerifyProof(tt.token(), http.MethodGet, "https://a.example.com", "")
+			assert.ErrorIs(t, err, ErrInvalidDPoPProof)
+		})
+	}
+}
+
+func TestDPoP_Middleware(t *testing.T) {
+	const htu = "http://example.com/resource"
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire)
+	clientJWK, err := NewJSONWebKey(edPublicKey, "", "")
+	require.NoError(t, err)
+	d := NewDPoP(m)
+	accessToken, err := d.GenerateToken(BoundMyClaims{Uid: 1}, clientJWK)
+	require.NoError(t, err)
+	handler := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clm, ok := ClaimsFromContext[BoundMyClaims](r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, int64(1), clm.Uid)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	clm, err := NewDPoPProofClaims(http.MethodGet, htu, accessToken, time.Now())
+	require.NoError(t, err)
+	proof, err := SignDPoPProof(jwt.SigningMethodEdDSA, edPrivateKey, clm)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		proof      string
+		wantStatus int
+		wantAuth   string
+	}{
+		{
+			name:       "normal",
+			proof:      proof,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "replayed",
+			proof:      proof,
+			wantStatus: http.StatusUnauthorized,
+			wantAuth:   `DPoP error="invalid_dpop_proof", algs="RS256 RS384 RS512 PS256 PS384 PS512 ES256 ES384 ES512 EdDSA"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, htu, nil)
+			r.Header.Set("Authorization", DPoPScheme+" "+accessToken)
+			r.Header.Set(DPoPHeader, tt.proof)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantAuth, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestDPoP_BoundTokenAsBearer(t *testing.T) {
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire)
+	clientJWK, err := NewJSONWebKey(edPublicKey, "", "")
+	require.NoError(t, err)
+	token, err := NewDPoP(m).GenerateToken(BoundMyClaims{Uid: 1}, clientJWK)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		manager *TokenManager[BoundMyClaims, *BoundMyClaims]
+	}{
+		{name: "plain", manager: m},
+		{name: "observed", manager: m.WithOptions(WithObserver[BoundMyClaims](ObserverFuncs{}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 绑定的 token 不能作为普通 Bearer token 使用
+			clm, err := tt.manager.VerifyToken(token)
+			assert.ErrorIs(t, err, ErrTokenNotBound)
+			assert.Equal(t, CategoryBinding, ErrorCategoryOf(err))
+			assert.Zero(t, clm)
+		})
+	}
+}
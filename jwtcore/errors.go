@@ -0,0 +1,52 @@
+package jwtcore
+
+import "errors"
+
+var (
+	// ErrUnsupportedKey 不支持的密钥类型.
+	ErrUnsupportedKey = errors.New("jwtcore: 不支持的密钥类型")
+	// ErrUnsupportedAlgorithm 不支持的签名算法.
+	ErrUnsupportedAlgorithm = errors.New("jwtcore: 不支持的签名算法")
+	// ErrAlgorithmNotAllowed token 的签名算法不被允许.
+	ErrAlgorithmNotAllowed = errors.New("jwtcore: 签名算法不被允许")
+	// ErrTypeMismatch token 的 typ 与预期不一致.
+	ErrTypeMismatch = errors.New("jwtcore: typ 不匹配")
+	// ErrInvalidCertificateChain x5c 证书链无效.
+	ErrInvalidCertificateChain = errors.New("jwtcore: 证书链无效")
+	// ErrTenantNotFound 没有与 token 对应的租户.
+	ErrTenantNotFound = errors.New("jwtcore: 未找到 token 对应的租户")
+	// ErrKeyNotFound 未找到用于校验签名的密钥.
+	ErrKeyNotFound = errors.New("jwtcore: 未找到匹配的密钥")
+	// ErrIssuerMismatch token 或配置文档中的 issuer 与预期不一致.
+	ErrIssuerMismatch = errors.New("jwtcore: issuer 不匹配")
+	// ErrAudienceMismatch token 的 aud 不包含预期的 audience.
+	ErrAudienceMismatch = errors.New("jwtcore: audience 不匹配")
+	// ErrClaimMissing token 缺少必需的 claim.
+	ErrClaimMissing = errors.New("jwtcore: 缺少必需的 claim")
+	// ErrTokenTooOld token 的签发时间超过了最大有效期.
+	ErrTokenTooOld = errors.New("jwtcore: token 超过最大有效期")
+	// ErrTokenTooLarge token 的长度超过限制.
+	ErrTokenTooLarge = errors.New("jwtcore: token 过长")
+	// ErrTokenTooDeep token 的 JSON 嵌套深度超过限制.
+	ErrTokenTooDeep = errors.New("jwtcore: token 的 JSON 嵌套过深")
+	// ErrTokenRevoked token 已被吊销.
+	ErrTokenRevoked = errors.New("jwtcore: token 已被吊销")
+	// ErrNonceMismatch ID token 的 nonce 与预期不一致.
+	ErrNonceMismatch = errors.New("jwtcore: nonce 不匹配")
+	// ErrAuthorizedPartyMismatch ID token 的 azp 与 client ID 不一致.
+	ErrAuthorizedPartyMismatch = errors.New("jwtcore: azp 不匹配")
+	// ErrAccessTokenHashMismatch ID token 的 at_hash 与 access token 不一致.
+	ErrAccessTokenHashMismatch = errors.New("jwtcore: at_hash 不匹配")
+	// ErrTokenNotFound 请求中没有 token.
+	ErrTokenNotFound = errors.New("jwtcore: 请求中没有 token")
+	// ErrTokenNotBound token 没有绑定持有者的密钥, 或与请求提供的密钥不一致.
+	ErrTokenNotBound = errors.New("jwtcore: token 与持有者密钥不匹配")
+	// ErrInvalidDPoPProof DPoP proof 无效.
+	ErrInvalidDPoPProof = errors.New("jwtcore: DPoP proof 无效")
+	// ErrReplayDetected jti 已被使用.
+	ErrReplayDetected = errors.New("jwtcore: jti 重复使用")
+	// ErrKMSUnavailable KMS 不可用.
+	ErrKMSUnavailable = errors.New("jwtcore: KMS 不可用")
+	// ErrInvalidSDJWT SD-JWT 无效或被篡改.
+	ErrInvalidSDJWT = errors.New("jwtcore: SD-JWT 无效")
+)
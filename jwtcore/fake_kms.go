@@ -0,0 +1,118 @@
+package jwtcore
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// FakeKMS 是基于本地密钥文件的模拟 KMS, 实现了 Signer 接口.
+// 可以模拟网络延迟与签名失败, 用于测试.
+type FakeKMS struct {
+	signer Signer
+
+	mu          sync.Mutex
+	latency     time.Duration
+	failureRate float64
+	failure     error
+	rand        *rand.Rand
+	calls       int
+}
+
+// A FakeKMSOption configures a FakeKMS.
+type FakeKMSOption interface {
+	apply(*FakeKMS)
+}
+
+// fakeKMSOptionFunc wraps a func, so it satisfies the FakeKMSOption interface.
+type fakeKMSOptionFunc func(*FakeKMS)
+
+func (f fakeKMSOptionFunc) apply(k *FakeKMS) {
+	f(k)
+}
+
+// WithFakeKMSLatency 设置每次签名的延迟.
+func WithFakeKMSLatency(latency time.Duration) FakeKMSOption {
+	return fakeKMSOptionFunc(func(k *FakeKMS) {
+		k.latency = latency
+	})
+}
+
+// WithFakeKMSFailureRate 设置签名随机失败的概率 [0, 1].
+// seed 用于生成随机数, 使测试结果可以复现.
+func WithFakeKMSFailureRate(rate float64, seed int64) FakeKMSOption {
+	return fakeKMSOptionFunc(func(k *FakeKMS) {
+		k.failureRate = rate
+		k.rand = rand.New(rand.NewSource(seed))
+	})
+}
+
+// NewFakeKMS 从密钥文件创建 FakeKMS.
+// 文件内容的格式与 TokenManager 的 EncryptionKey 相同:
+// HMAC 为原始密钥, RSA/ECDSA/EdDSA 为 PEM 编码的私钥.
+func NewFakeKMS(path string, method jwt.SigningMethod, kid string,
+	opts ...FakeKMSOption) (*FakeKMS, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parseSigningKey(method, string(b))
+	if err != nil {
+		return nil, err
+	}
+	k := &FakeKMS{signer: NewKeySigner(method, key, kid)}
+	for _, opt := range opts {
+		opt.apply(k)
+	}
+	return k, nil
+}
+
+// SetFailure 设置签名失败时返回的错误, 为 nil 时恢复正常.
+func (k *FakeKMS) SetFailure(err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.failure = err
+}
+
+// Calls 返回 Sign 被调用的次数.
+func (k *FakeKMS) Calls() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.calls
+}
+
+func (k *FakeKMS) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	k.mu.Lock()
+	k.calls++
+	latency, err := k.latency, k.failure
+	if err == nil && k.rand != nil && k.rand.Float64() < k.failureRate {
+		err = ErrKMSUnavailable
+	}
+	k.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k.signer.Sign(ctx, signingInput)
+}
+
+func (k *FakeKMS) Algorithm() string {
+	return k.signer.Algorithm()
+}
+
+func (k *FakeKMS) KeyID() string {
+	return k.signer.KeyID()
+}
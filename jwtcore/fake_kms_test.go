@@ -0,0 +1,89 @@
+package jwtcore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestFakeKMS_Sign(t *testing.T) {
+	kms, err := NewFakeKMS(writeKeyFile(t, ecPrivateKeyPEM), jwt.SigningMethodES256, "kms-1")
+	require.NoError(t, err)
+	m := NewTokenManager[MyClaims]("", defaultExpire,
+		WithSigner[MyClaims](kms),
+		WithDecryptKey[MyClaims](ecPublicKeyPEM),
+	)
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, kms.Calls())
+
+	// 模拟 KMS 故障
+	kms.SetFailure(ErrKMSUnavailable)
+	_, err = m.GenerateToken(MyClaims{Uid: 1})
+	var signErr *SignError
+	assert.True(t, errors.As(err, &signErr))
+	assert.ErrorIs(t, err, ErrKMSUnavailable)
+
+	kms.SetFailure(nil)
+	_, err = m.GenerateToken(MyClaims{Uid: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, kms.Calls())
+}
+
+func TestFakeKMS_Latency(t *testing.T) {
+	kms, err := NewFakeKMS(writeKeyFile(t, encryptionKey), jwt.SigningMethodHS256, "",
+		WithFakeKMSLatency(time.Second))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = kms.Sign(ctx, "input")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFakeKMS_FailureRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		wantFails int
+	}{
+		{name: "never", rate: 0, wantFails: 0},
+		{name: "always", rate: 1, wantFails: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kms, err := NewFakeKMS(writeKeyFile(t, encryptionKey), jwt.SigningMethodHS256, "",
+				WithFakeKMSFailureRate(tt.rate, 1))
+			require.NoError(t, err)
+			fails := 0
+			for i := 0; i < 10; i++ {
+				if _, err = kms.Sign(context.Background(), "input"); err != nil {
+					assert.ErrorIs(t, err, ErrKMSUnavailable)
+					fails++
+				}
+			}
+			assert.Equal(t, tt.wantFails, fails)
+		})
+	}
+}
+
+func TestNewFakeKMS_BadKeyFile(t *testing.T) {
+	_, err := NewFakeKMS(filepath.Join(t.TempDir(), "missing.pem"), jwt.SigningMethodRS256, "")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = NewFakeKMS(writeKeyFile(t, "not a pem"), jwt.SigningMethodRS256, "")
+	assert.ErrorIs(t, err, jwt.ErrKeyMustBePEMEncoded)
+}
@@ -0,0 +1,165 @@
+package jwtcore
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// setHeader 设置签发 token 时的 JOSE 头部.
+// 复制 headers 后再修改, 避免影响 WithOptions 之前的 TokenManager.
+func (t *TokenManager[T, PT]) setHeader(key string, value any) {
+	headers := make(map[string]any, len(t.headers)+1)
+	for k, v := range t.headers {
+		headers[k] = v
+	}
+	headers[key] = value
+	t.headers = headers
+}
+
+// buildHeader 设置 token 的 JOSE 头部.
+// 优先级从低到高为: 头部选项、Signer 的 kid、genHeaderFn; alg 始终由 Signer 决定.
+func (t *TokenManager[T, PT]) buildHeader(header map[string]any, signer Signer) {
+	for k, v := range t.headers {
+		header[k] = v
+	}
+	if kid := signer.KeyID(); kid != "" {
+		header["kid"] = kid
+	}
+	if t.genHeaderFn != nil {
+		for k, v := range t.genHeaderFn() {
+			header[k] = v
+		}
+	}
+	header["alg"] = signer.Algorithm()
+}
+
+// checkType 校验 token 的 typ 是否为 WithExpectedType 设置的类型.
+func (t *TokenManager[T, PT]) checkType(value any) error {
+	if t.expectedType == "" {
+		return nil
+	}
+	typ, _ := value.(string)
+	if !equalMediaType(typ, t.expectedType) {
+		return fmt.Errorf("%w: %q", ErrTypeMismatch, typ)
+	}
+	return nil
+}
+
+// equalMediaType 比较 typ, 忽略大小写与 "application/" 前缀.
+// See https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.9
+func equalMediaType(a, b string) bool {
+	const prefix = "application/"
+	trim := func(s string) string {
+		if len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return s[len(prefix):]
+		}
+		return s
+	}
+	return strings.EqualFold(trim(a), trim(b))
+}
+
+// verifyKey 返回校验签名的公钥.
+// 设置了 WithX509Roots 且 token 带有 x5c 时, 校验证书链与叶子证书并使用叶子证书的公钥,
+// 否则使用 DecryptKey.
+func (t *TokenManager[T, PT]) verifyKey(token *jwt.Token) (any, error) {
+	x5c, ok := token.Header["x5c"]
+	if t.x509Roots == nil || !ok {
+		return t.decryptKey(token.Method)
+	}
+	if _, ok = token.Method.(*jwt.SigningMethodHMAC); ok {
+		return nil, fmt.Errorf("%w: 不能使用证书进行 HMAC 校验", ErrAlgorithmNotAllowed)
+	}
+	leaf, err := t.verifyCertChain(x5c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificateChain, err)
+	}
+	if t.x509VerifyLeaf == nil {
+		return nil, fmt.Errorf("%w: 没有设置叶子证书的校验", ErrInvalidCertificateChain)
+	}
+	if err = t.x509VerifyLeaf(leaf); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificateChain, err)
+	}
+	if x5t, ok := token.Header["x5t"]; ok && x5t != certificateSHA1Thumbprint(leaf) {
+		return nil, fmt.Errorf("%w: x5t 与证书不匹配", ErrInvalidCertificateChain)
+	}
+	return leaf.PublicKey, nil
+}
+
+// X509LeafVerifier 校验 x5c 证书链的叶子证书, 返回 error 时拒绝该 token.
+type X509LeafVerifier func(leaf *x509.Certificate) error
+
+// X509LeafDNSName 要求叶子证书的 SAN 包含 DNS 名称 name.
+func X509LeafDNSName(name string) X509LeafVerifier {
+	return func(leaf *x509.Certificate) error {
+		return leaf.VerifyHostname(name)
+	}
+}
+
+// X509LeafSubject 要求叶子证书的主题 CN 为 commonName.
+func X509LeafSubject(commonName string) X509LeafVerifier {
+	return func(leaf *x509.Certificate) error {
+		if leaf.Subject.CommonName != commonName {
+			return fmt.Errorf("证书主题 %q 与 %q 不一致", leaf.Subject.CommonName, commonName)
+		}
+		return nil
+	}
+}
+
+// verifyCertChain 解析 x5c 并使用 WithX509Roots 设置的根证书校验证书链, 返回叶子证书.
+func (t *TokenManager[T, PT]) verifyCertChain(x5c any) (*x509.Certificate, error) {
+	values, ok := x5c.([]any)
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("x5c 格式错误")
+	}
+	certs := make([]*x509.Certificate, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("x5c 格式错误")
+		}
+		// x5c 使用标准 base64 编码, 而不是 base64url.
+		der, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         t.x509Roots,
+		Intermediates: intermediates,
+		CurrentTime:   t.timeFunc(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+// encodeCertChain 将证书链编码为 x5c.
+func encodeCertChain(chain []*x509.Certificate) []string {
+	x5c := make([]string, 0, len(chain))
+	for _, cert := range chain {
+		x5c = append(x5c, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+	return x5c
+}
+
+// certificateSHA1Thumbprint 计算证书的 SHA-1 指纹 (x5t).
+func certificateSHA1Thumbprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return encodeBase64(sum[:])
+}
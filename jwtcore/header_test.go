@@ -0,0 +1,267 @@
+package jwtcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenManager_GenerateToken_Header(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	tests := []struct {
+		name   string
+		opts   []Option[MyClaims, *MyClaims]
+		want   map[string]any
+		signer Signer
+	}{
+		{
+			name: "default",
+			want: map[string]any{"alg": "HS256", "typ": "JWT"},
+		},
+		{
+			name: "set_headers",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithType[MyClaims]("at+jwt"),
+				WithContentType[MyClaims]("JWT"),
+				WithKeyID[MyClaims]("key-1"),
+				WithJWKSetURL[MyClaims]("https://example.com/jwks.json"),
+				WithX509CertChain[MyClaims](ca.Leaf),
+			},
+			want: map[string]any{
+				"alg": "HS256",
+				"typ": "at+jwt",
+				"cty": "JWT",
+				"kid": "key-1",
+				"jku": "https://example.com/jwks.json",
+				"x5c": []any{base64.StdEncoding.EncodeToString(ca.Leaf.Raw)},
+				"x5t": certificateSHA1Thumbprint(ca.Leaf),
+			},
+		},
+		{
+			name: "signer_key_id",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithKeyID[MyClaims]("key-1"),
+				WithSigner[MyClaims](NewKeySigner(jwt.SigningMethodHS256,
+					[]byte(encryptionKey), "signer-key")),
+			},
+			want: map[string]any{"alg": "HS256", "typ": "JWT", "kid": "signer-key"},
+		},
+		{
+			// genHeaderFn 覆盖其他头部选项, alg 除外.
+			name: "gen_header_func",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithType[MyClaims]("at+jwt"),
+				WithGenHeaderFunc[MyClaims](func() map[string]any {
+					return map[string]any{"typ": "JWT", "alg": "none", "tenant": "a"}
+				}),
+			},
+			want: map[string]any{"alg": "HS256", "typ": "JWT", "tenant": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, tt.opts...)
+			token, err := m.GenerateToken(MyClaims{Uid: 1})
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &MyClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, parsed.Header)
+			_, err = m.VerifyToken(token)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWithType_DoesNotAffectOriginal(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, WithType[MyClaims]("at+jwt"))
+	_ = m.WithOptions(WithType[MyClaims]("id+jwt"), WithKeyID[MyClaims]("key-1"))
+	assert.Equal(t, map[string]any{"typ": "at+jwt"}, m.headers)
+}
+
+func TestTokenManager_VerifyToken_ExpectedType(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		expected string
+		wantErr  error
+	}{
+		{name: "not_expected", typ: "JWT"},
+		{name: "matched", typ: "at+jwt", expected: "at+jwt"},
+		{name: "media_type", typ: "application/AT+JWT", expected: "at+jwt"},
+		{name: "mismatch", typ: "JWT", expected: "at+jwt", wantErr: ErrTypeMismatch},
+		{name: "id_token", typ: "id_token+jwt", expected: "at+jwt", wantErr: ErrTypeMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+				WithType[MyClaims](tt.typ)).GenerateToken(MyClaims{Uid: 1})
+			require.NoError(t, err)
+			m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+				WithExpectedType[MyClaims](tt.expected))
+			_, err = m.VerifyToken(token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTokenManager_VerifyToken_X509Chain(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	leaf := newTestCertificate(t, "signer", &ca)
+	// other 与 leaf 由同一个根证书签发
+	other := newTestCertificate(t, "other", &ca)
+	untrusted := newTestCertificate(t, "untrusted", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	// issue 使用 cert 的私钥签发带有 x5c 的 token.
+	issue := func(cert tls.Certificate, opts ...Option[MyClaims, *MyClaims]) string {
+		signer, err := NewCryptoSigner(cert.PrivateKey.(*ecdsa.PrivateKey),
+			jwt.SigningMethodES256, "")
+		require.NoError(t, err)
+		opts = append([]Option[MyClaims, *MyClaims]{
+			WithSigner[MyClaims](signer),
+			WithX509CertChain[MyClaims](cert.Leaf),
+		}, opts...)
+		token, err := NewTokenManager[MyClaims]("", defaultExpire, opts...).
+			GenerateToken(MyClaims{Uid: 1})
+		require.NoError(t, err)
+		return token
+	}
+	verifier := NewTokenManager[MyClaims]("", defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodES256),
+		WithX509Roots[MyClaims](roots, X509LeafSubject("signer")),
+	)
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "trusted",
+			token: issue(leaf),
+		},
+		{
+			name: "with_chain",
+			token: issue(leaf, WithGenHeaderFunc[MyClaims](func() map[string]any {
+				return map[string]any{"x5c": encodeCertChain(
+					[]*x509.Certificate{leaf.Leaf, ca.Leaf})}
+			})),
+		},
+		{
+			name:    "untrusted",
+			token:   issue(untrusted),
+			wantErr: ErrInvalidCertificateChain,
+		},
+		{
+			name:    "other_leaf",
+			token:   issue(other),
+			wantErr: ErrInvalidCertificateChain,
+		},
+		{
+			// 使用不受信任的私钥签名, 但附带受信任的证书
+			name:    "key_mismatch",
+			token:   issue(untrusted, WithX509CertChain[MyClaims](leaf.Leaf)),
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "x5t_mismatch",
+			token: issue(leaf, WithGenHeaderFunc[MyClaims](func() map[string]any {
+				return map[string]any{"x5t": certificateSHA1Thumbprint(untrusted.Leaf)}
+			})),
+			wantErr: ErrInvalidCertificateChain,
+		},
+		{
+			name: "bad_x5c",
+			token: issue(leaf, WithGenHeaderFunc[MyClaims](func() map[string]any {
+				return map[string]any{"x5c": []string{"bad"}}
+			})),
+			wantErr: ErrInvalidCertificateChain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.VerifyToken(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestWithX509Roots_VerifyLeaf(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	leaf := newTestCertificate(t, "signer", &ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	signer, err := NewCryptoSigner(leaf.PrivateKey.(*ecdsa.PrivateKey), jwt.SigningMethodES256, "")
+	require.NoError(t, err)
+	token, err := NewTokenManager[MyClaims]("", defaultExpire,
+		WithSigner[MyClaims](signer),
+		WithX509CertChain[MyClaims](leaf.Leaf),
+	).GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		verifyLeaf X509LeafVerifier
+		wantErr    error
+	}{
+		{
+			name:       "dns_name",
+			verifyLeaf: X509LeafDNSName("signer.example.com"),
+		},
+		{
+			name:       "dns_name_mismatch",
+			verifyLeaf: X509LeafDNSName("other.example.com"),
+			wantErr:    ErrInvalidCertificateChain,
+		},
+		{
+			name:       "subject_mismatch",
+			verifyLeaf: X509LeafSubject("other"),
+			wantErr:    ErrInvalidCertificateChain,
+		},
+		{
+			// 没有设置叶子证书的校验时拒绝所有带有 x5c 的 token
+			name:    "nil",
+			wantErr: ErrInvalidCertificateChain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTokenManager[MyClaims]("", defaultExpire,
+				WithMethod[MyClaims](jwt.SigningMethodES256),
+				WithX509Roots[MyClaims](roots, tt.verifyLeaf),
+			).VerifyToken(token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTokenManager_VerifyToken_X509ChainHMAC(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	token, err := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithX509CertChain[MyClaims](ca.Leaf)).GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithX509Roots[MyClaims](roots, X509LeafSubject("ca"))).VerifyToken(token)
+	assert.ErrorIs(t, err, ErrAlgorithmNotAllowed)
+}
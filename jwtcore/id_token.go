@@ -0,0 +1,141 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IDTokenClaims 是 OpenID Connect ID token 的 claims.
+// See https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+type IDTokenClaims struct {
+	// the `auth_time` claim. 终端用户完成认证的时间.
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+
+	// the `nonce` claim. 用于关联客户端会话与 ID token, 防止重放攻击.
+	Nonce string `json:"nonce,omitempty"`
+
+	// the `acr` (Authentication Context Class Reference) claim.
+	ACR string `json:"acr,omitempty"`
+
+	// the `amr` (Authentication Methods References) claim.
+	AMR []string `json:"amr,omitempty"`
+
+	// the `azp` (Authorized party) claim.
+	AuthorizedParty string `json:"azp,omitempty"`
+
+	// the `at_hash` (Access Token hash) claim.
+	AccessTokenHash string `json:"at_hash,omitempty"`
+
+	// the `c_hash` (Code hash) claim.
+	CodeHash string `json:"c_hash,omitempty"`
+
+	RegisteredClaims
+}
+
+// IDClaims 是可以作为 ID token 签发与校验的 claims.
+type IDClaims[T jwt.Claims] interface {
+	Claims[T]
+
+	GetNonce() string
+	GetAuthorizedParty() string
+	GetAccessTokenHash() string
+	SetAccessTokenHash(hash string)
+	SetCodeHash(hash string)
+}
+
+func (c IDTokenClaims) GetNonce() string {
+	return c.Nonce
+}
+
+func (c IDTokenClaims) GetAuthorizedParty() string {
+	return c.AuthorizedParty
+}
+
+func (c IDTokenClaims) GetAccessTokenHash() string {
+	return c.AccessTokenHash
+}
+
+func (c *IDTokenClaims) SetAccessTokenHash(hash string) {
+	c.AccessTokenHash = hash
+}
+
+func (c *IDTokenClaims) SetCodeHash(hash string) {
+	c.CodeHash = hash
+}
+
+// IDTokenIssuer 签发 OpenID Connect ID token.
+type IDTokenIssuer[T jwt.Claims, PT IDClaims[T]] struct {
+	manager *TokenManager[T, PT]
+}
+
+// NewIDTokenIssuer 使用 jwt 管理器创建 ID token 签发器.
+// ID token 的签发人、有效期与签名方式均由 manager 决定.
+func NewIDTokenIssuer[T jwt.Claims, PT IDClaims[T]](
+	manager *TokenManager[T, PT]) *IDTokenIssuer[T, PT] {
+	return &IDTokenIssuer[T, PT]{manager: manager}
+}
+
+// IssueIDToken 签发 ID token.
+// accessToken 与 code 不为空时, 根据签名算法分别计算 at_hash 与 c_hash.
+func (i *IDTokenIssuer[T, PT]) IssueIDToken(clm T, accessToken, code string) (string, error) {
+	return i.IssueIDTokenContext(context.Background(), clm, accessToken, code)
+}
+
+// IssueIDTokenContext 与 IssueIDToken 相同, ctx 会传递给 jwt 管理器.
+func (i *IDTokenIssuer[T, PT]) IssueIDTokenContext(ctx context.Context, clm T,
+	accessToken, code string) (string, error) {
+	p := PT(&clm)
+	alg := i.manager.Method.Alg()
+	if accessToken != "" {
+		hash, err := TokenHash(alg, accessToken)
+		if err != nil {
+			return "", err
+		}
+		p.SetAccessTokenHash(hash)
+	}
+	if code != "" {
+		hash, err := TokenHash(alg, code)
+		if err != nil {
+			return "", err
+		}
+		p.SetCodeHash(hash)
+	}
+	return i.manager.GenerateTokenContext(ctx, clm)
+}
+
+// TokenHash 计算 at_hash/c_hash.
+// 使用签名算法对应的哈希函数计算 value 的哈希值, 取左半部分进行 base64url 编码.
+// See https://openid.net/specs/openid-connect-core-1_0.html#CodeIDToken
+func TokenHash(alg, value string) (string, error) {
+	h, err := hashForAlgorithm(alg)
+	if err != nil {
+		return "", err
+	}
+	hasher := h.New()
+	hasher.Write([]byte(value))
+	sum := hasher.Sum(nil)
+	return encodeBase64(sum[:len(sum)/2]), nil
+}
+
+// hashForAlgorithm 返回签名算法对应的哈希函数.
+// EdDSA(Ed25519) 使用 SHA-512.
+func hashForAlgorithm(alg string) (crypto.Hash, error) {
+	switch {
+	case alg == jwt.SigningMethodEdDSA.Alg():
+		return crypto.SHA512, nil
+	case alg == jwt.SigningMethodNone.Alg():
+		return 0, ErrUnsupportedAlgorithm
+	case strings.HasSuffix(alg, "256"):
+		return crypto.SHA256, nil
+	case strings.HasSuffix(alg, "384"):
+		return crypto.SHA384, nil
+	case strings.HasSuffix(alg, "512"):
+		return crypto.SHA512, nil
+	}
+	return 0, ErrUnsupportedAlgorithm
+}
@@ -0,0 +1,118 @@
+package jwtcore
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		alg     string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{
+			// https://openid.net/specs/openid-connect-core-1_0.html#id_tokenExample
+			name:  "rs256_access_token",
+			alg:   "RS256",
+			value: "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			want:  "77QmUPtjPfzWtF2AnpK9RQ",
+		},
+		{
+			name:  "rs256_code",
+			alg:   "RS256",
+			value: "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk",
+			want:  "LDktKdoQak3Pk0cnXxCltA",
+		},
+		{
+			name:  "hs384",
+			alg:   "HS384",
+			value: "abc",
+			want:  "ywB1P0WjXou1oD1pmsZQBycsMqsO3tFj",
+		},
+		{
+			name:  "eddsa",
+			alg:   "EdDSA",
+			value: "abc",
+			want:  "3a81oZNherrMQXNJriBBMRLm-k6JqX6iCp7u5ktV05o",
+		},
+		{
+			name:    "none",
+			alg:     "none",
+			value:   "abc",
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name:    "unknown",
+			alg:     "foo",
+			value:   "abc",
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TokenHash(tt.alg, tt.value)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIDTokenIssuer_IssueIDToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		accessToken string
+		code        string
+		wantAtHash  string
+		wantCHash   string
+	}{
+		{
+			name: "without_hash",
+		},
+		{
+			name:        "with_access_token",
+			accessToken: "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			wantAtHash:  "77QmUPtjPfzWtF2AnpK9RQ",
+		},
+		{
+			name:        "with_access_token_and_code",
+			accessToken: "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			code:        "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk",
+			wantAtHash:  "77QmUPtjPfzWtF2AnpK9RQ",
+			wantCHash:   "LDktKdoQak3Pk0cnXxCltA",
+		},
+	}
+	m := NewTokenManager[IDTokenClaims](rsaPrivateKeyPEM, defaultExpire,
+		WithMethod[IDTokenClaims](jwt.SigningMethodRS256),
+		WithDecryptKey[IDTokenClaims](rsaPublicKeyPEM),
+		WithIssuer[IDTokenClaims]("https://idp.example.com"),
+	)
+	issuer := NewIDTokenIssuer(m)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clm := IDTokenClaims{Nonce: "n-0S6_WzA2Mj", AMR: []string{"pwd"}}
+			token, err := issuer.IssueIDToken(clm, tt.accessToken, tt.code)
+			require.NoError(t, err)
+			got, err := m.VerifyToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, "https://idp.example.com", got.Issuer)
+			assert.Equal(t, "n-0S6_WzA2Mj", got.Nonce)
+			assert.Equal(t, []string{"pwd"}, got.AMR)
+			assert.Equal(t, tt.wantAtHash, got.AccessTokenHash)
+			assert.Equal(t, tt.wantCHash, got.CodeHash)
+		})
+	}
+}
+
+func TestIDTokenIssuer_UnsupportedMethod(t *testing.T) {
+	m := NewTokenManager[IDTokenClaims]("", defaultExpire,
+		WithMethod[IDTokenClaims](jwt.SigningMethodNone),
+	)
+	_, err := NewIDTokenIssuer(m).IssueIDToken(IDTokenClaims{}, "at", "")
+	assert.Equal(t, ErrUnsupportedAlgorithm, err)
+}
@@ -0,0 +1,211 @@
+package jwtcore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+)
+
+// JSONWebKey 表示一个 JSON Web Key 公钥.
+// See https://datatracker.ietf.org/doc/html/rfc7517#section-4
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA 公钥参数.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC/OKP 公钥参数.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet 表示一组 JSON Web Key.
+// See https://datatracker.ietf.org/doc/html/rfc7517#section-5
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey 将公钥转换为 JSONWebKey.
+// 支持 *rsa.PublicKey, *ecdsa.PublicKey 与 ed25519.PublicKey.
+func NewJSONWebKey(key crypto.PublicKey, kid, alg string) (JSONWebKey, error) {
+	jwk := JSONWebKey{KeyID: kid, Use: "sig", Algorithm: alg}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeBase64(k.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = k.Curve.Params().Name
+		jwk.X = encodeBase64(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeBase64(k)
+	default:
+		return JSONWebKey{}, ErrUnsupportedKey
+	}
+	return jwk, nil
+}
+
+// PublicKey 将 JSONWebKey 转换为公钥.
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBase64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exp.IsInt64() || exp.Int64() <= 1 || exp.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("%w: RSA 参数错误", ErrUnsupportedKey)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		curve, ok := curveByName(k.Curve)
+		if !ok {
+			return nil, fmt.Errorf("%w: 未知的曲线 %q", ErrUnsupportedKey, k.Curve)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: 点不在曲线上", ErrUnsupportedKey)
+		}
+		return key, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("%w: 未知的曲线 %q", ErrUnsupportedKey, k.Curve)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: Ed25519 公钥长度错误", ErrUnsupportedKey)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedKey, k.KeyType)
+}
+
+// Thumbprint 计算 JSONWebKey 的 SHA-256 指纹.
+// See https://datatracker.ietf.org/doc/html/rfc7638
+func (k JSONWebKey) Thumbprint() (string, error) {
+	var members any
+	switch k.KeyType {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.KeyType, k.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Curve, k.KeyType, k.X, k.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{k.Curve, k.KeyType, k.X}
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedKey, k.KeyType)
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return encodeBase64(sum[:]), nil
+}
+
+// LookupKeyID 返回 kid 匹配的 JSONWebKey.
+func (s JSONWebKeySet) LookupKeyID(kid string) []JSONWebKey {
+	var keys []JSONWebKey
+	for _, k := range s.Keys {
+		if k.KeyID == kid {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// JWKSHandler 返回发布 JSONWebKeySet 的 http.Handler.
+func JWKSHandler(set JSONWebKeySet) http.Handler {
+	return jsonHandler(set)
+}
+
+// jsonHandler 以 JSON 格式响应 GET 请求.
+func jsonHandler(v any) http.Handler {
+	body, err := json.Marshal(v)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+				http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// curveByName 根据 JWK crv 参数获取椭圆曲线.
+func curveByName(name string) (elliptic.Curve, bool) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), true
+	case "P-384":
+		return elliptic.P384(), true
+	case "P-521":
+		return elliptic.P521(), true
+	}
+	return nil, false
+}
@@ -0,0 +1,122 @@
+package jwtcore
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJSONWebKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       any
+		wantType  string
+		wantCurve string
+		wantErr   error
+	}{
+		{
+			name:     "rsa",
+			key:      &rsaPrivateKey.PublicKey,
+			wantType: "RSA",
+		},
+		{
+			name:      "ecdsa",
+			key:       &ecPrivateKey.PublicKey,
+			wantType:  "EC",
+			wantCurve: "P-256",
+		},
+		{
+			name:      "ed25519",
+			key:       edPublicKey,
+			wantType:  "OKP",
+			wantCurve: "Ed25519",
+		},
+		{
+			name:    "unsupported",
+			key:     []byte("sign key"),
+			wantErr: ErrUnsupportedKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewJSONWebKey(tt.key, "kid", "alg")
+			assert.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tt.wantType, got.KeyType)
+			assert.Equal(t, tt.wantCurve, got.Curve)
+			assert.Equal(t, "kid", got.KeyID)
+			assert.Equal(t, "sig", got.Use)
+		})
+	}
+}
+
+func TestJSONWebKey_PublicKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     any
+		wantErr error
+	}{
+		{name: "rsa", key: &rsaPrivateKey.PublicKey},
+		{name: "ecdsa", key: &ecPrivateKey.PublicKey},
+		{name: "ed25519", key: edPublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jwk, err := NewJSONWebKey(tt.key, "", "")
+			require.NoError(t, err)
+			got, err := jwk.PublicKey()
+			require.NoError(t, err)
+			assert.Equal(t, tt.key, got)
+		})
+	}
+
+	_, err := JSONWebKey{KeyType: "EC", Curve: "P-256", X: "AA", Y: "AA"}.PublicKey()
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+	_, err = JSONWebKey{KeyType: "oct"}.PublicKey()
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	jwk, err := NewJSONWebKey(&rsaPrivateKey.PublicKey, "1", "RS256")
+	require.NoError(t, err)
+	set := JSONWebKeySet{Keys: []JSONWebKey{jwk}}
+	tests := []struct {
+		name       string
+		method     string
+		wantStatus int
+	}{
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "post",
+			method:     http.MethodPost,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			JWKSHandler(set).ServeHTTP(rec,
+				httptest.NewRequest(tt.method, "/jwks.json", nil))
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if rec.Code != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			body, _ := io.ReadAll(rec.Body)
+			var got JSONWebKeySet
+			require.NoError(t, json.Unmarshal(body, &got))
+			assert.Equal(t, set, got)
+		})
+	}
+}
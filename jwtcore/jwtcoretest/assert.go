@@ -0,0 +1,41 @@
+package jwtcoretest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/udugong/token"
+)
+
+// AssertVerifyOK 断言 token 校验通过, 并返回 claims.
+func AssertVerifyOK[T any](tb testing.TB, m token.Manager[T], tokenString string) T {
+	tb.Helper()
+	clm, err := m.VerifyToken(tokenString)
+	if err != nil {
+		tb.Errorf("jwtcoretest: 校验 token 失败: %v", err)
+	}
+	return clm
+}
+
+// AssertVerifyErrorIs 断言 token 校验失败, 错误链中包含 target, 并且返回零值的 claims.
+func AssertVerifyErrorIs[T any](tb testing.TB, m token.Manager[T], tokenString string,
+	target error) bool {
+	tb.Helper()
+	clm, err := m.VerifyToken(tokenString)
+	if err == nil {
+		tb.Errorf("jwtcoretest: token 校验通过, 期望错误 %v", target)
+		return false
+	}
+	ok := true
+	if !errors.Is(err, target) {
+		tb.Errorf("jwtcoretest: 校验错误 %q 不包含 %q", err, target)
+		ok = false
+	}
+	var zero T
+	if !reflect.DeepEqual(clm, zero) {
+		tb.Errorf("jwtcoretest: 校验失败时返回了非零值的 claims: %+v", clm)
+		ok = false
+	}
+	return ok
+}
@@ -0,0 +1,83 @@
+package jwtcoretest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder 记录断言失败的信息.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// stubManager 返回固定结果的 token.Manager.
+type stubManager struct {
+	clm MyClaims
+	err error
+}
+
+func (m stubManager) GenerateToken(MyClaims) (string, error) { return "", nil }
+
+func (m stubManager) VerifyToken(string) (MyClaims, error) { return m.clm, m.err }
+
+func TestAssertVerifyErrorIs(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name       string
+		manager    stubManager
+		want       bool
+		wantErrors int
+	}{
+		{
+			name:    "ok",
+			manager: stubManager{err: fmt.Errorf("验证失败: %w", errBoom)},
+			want:    true,
+		},
+		{
+			name:       "verified",
+			manager:    stubManager{clm: MyClaims{Uid: 1}},
+			want:       false,
+			wantErrors: 1,
+		},
+		{
+			name:       "other_error",
+			manager:    stubManager{err: errors.New("other")},
+			want:       false,
+			wantErrors: 1,
+		},
+		{
+			name:       "non_zero_claims",
+			manager:    stubManager{clm: MyClaims{Uid: 1}, err: errBoom},
+			want:       false,
+			wantErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			got := AssertVerifyErrorIs[MyClaims](r, tt.manager, "token", errBoom)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, r.errors, tt.wantErrors)
+		})
+	}
+}
+
+func TestAssertVerifyOK(t *testing.T) {
+	r := &recorder{}
+	got := AssertVerifyOK[MyClaims](r, stubManager{clm: MyClaims{Uid: 1}}, "token")
+	assert.Equal(t, int64(1), got.Uid)
+	assert.Empty(t, r.errors)
+
+	AssertVerifyOK[MyClaims](r, stubManager{err: errors.New("boom")}, "token")
+	assert.Len(t, r.errors, 1)
+}
@@ -0,0 +1,41 @@
+// Package jwtcoretest 提供测试 jwtcore 的工具: 可控的时钟、签发各种 token 的 TestIssuer、
+// 断言函数与基于 httptest 的 OpenID Provider.
+package jwtcoretest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock 是可以手动控制的时钟, 可以安全地在多个 goroutine 中使用.
+// 使用 jwtcore.WithTimeFunc(clock.Now) 控制 jwt 管理器的时间.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock 创建当前时间为 now 的时钟.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now 返回时钟的当前时间.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 将时钟拨快 d, d 为负数时拨慢.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set 设置时钟的当前时间.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
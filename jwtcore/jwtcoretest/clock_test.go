@@ -0,0 +1,21 @@
+package jwtcoretest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var nowTime = time.UnixMilli(1695571200000)
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(nowTime)
+	assert.Equal(t, nowTime, clock.Now())
+	clock.Advance(time.Minute)
+	assert.Equal(t, nowTime.Add(time.Minute), clock.Now())
+	clock.Advance(-2 * time.Minute)
+	assert.Equal(t, nowTime.Add(-time.Minute), clock.Now())
+	clock.Set(nowTime)
+	assert.Equal(t, nowTime, clock.Now())
+}
@@ -0,0 +1,134 @@
+package jwtcoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/udugong/token/jwtcore"
+)
+
+// FakeIdP 是基于 httptest.Server 的 OpenID Provider, 发布配置文档与 JWKS.
+// 使用 ES256 签名, 测试结束时自动关闭.
+type FakeIdP struct {
+	server       *httptest.Server
+	jwksRequests atomic.Int32
+
+	mu      sync.Mutex
+	keys    []*ecdsa.PrivateKey // 所有发布的密钥, 最后一个为当前的签名密钥
+	jwks    jwtcore.JSONWebKeySet
+	current string // 当前签名密钥的 kid
+}
+
+// NewFakeIdP 创建并启动 FakeIdP.
+func NewFakeIdP(tb testing.TB) *FakeIdP {
+	tb.Helper()
+	idp := &FakeIdP{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(jwtcore.DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		jwtcore.DiscoveryHandler(jwtcore.ProviderMetadata{
+			Issuer:                           idp.Issuer(),
+			JWKSURI:                          idp.JWKSURL(),
+			ResponseTypesSupported:           []string{"code"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: []string{jwt.SigningMethodES256.Alg()},
+		}).ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksRequests.Add(1)
+		idp.mu.Lock()
+		jwks := idp.jwks
+		idp.mu.Unlock()
+		jwtcore.JWKSHandler(jwks).ServeHTTP(w, r)
+	})
+	idp.server = httptest.NewServer(mux)
+	tb.Cleanup(idp.server.Close)
+	idp.RotateKey(tb)
+	return idp
+}
+
+// Issuer 返回 issuer URL.
+func (idp *FakeIdP) Issuer() string {
+	return idp.server.URL
+}
+
+// JWKSURL 返回 JWKS 的 URL.
+func (idp *FakeIdP) JWKSURL() string {
+	return idp.server.URL + "/jwks.json"
+}
+
+// Client 返回访问 FakeIdP 的 http.Client.
+func (idp *FakeIdP) Client() *http.Client {
+	return idp.server.Client()
+}
+
+// JWKSRequests 返回 JWKS 被请求的次数.
+func (idp *FakeIdP) JWKSRequests() int {
+	return int(idp.jwksRequests.Load())
+}
+
+// KeyID 返回当前签名密钥的 kid.
+func (idp *FakeIdP) KeyID() string {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.current
+}
+
+// RotateKey 生成新的签名密钥并发布到 JWKS, 旧的密钥仍然保留在 JWKS 中.
+func (idp *FakeIdP) RotateKey(tb testing.TB) {
+	tb.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		tb.Fatalf("jwtcoretest: 生成密钥失败: %v", err)
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	kid := fmt.Sprintf("key-%d", len(idp.keys)+1)
+	jwk, err := jwtcore.NewJSONWebKey(&key.PublicKey, kid, jwt.SigningMethodES256.Alg())
+	if err != nil {
+		tb.Fatalf("jwtcoretest: 创建 JWK 失败: %v", err)
+	}
+	idp.keys = append(idp.keys, key)
+	idp.jwks.Keys = append(idp.jwks.Keys, jwk)
+	idp.current = kid
+}
+
+// Signer 返回当前签名密钥的 Signer, 签发的 token 头部带有 kid.
+func (idp *FakeIdP) Signer() jwtcore.Signer {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return jwtcore.NewKeySigner(jwt.SigningMethodES256, idp.keys[len(idp.keys)-1], idp.current)
+}
+
+// PublicKeyPEM 返回当前签名密钥的 PEM 编码的公钥.
+func (idp *FakeIdP) PublicKeyPEM() string {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	der, err := x509.MarshalPKIXPublicKey(&idp.keys[len(idp.keys)-1].PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// NewIdPManager 创建使用 FakeIdP 当前签名密钥的 jwt 管理器, iss 为 FakeIdP 的 issuer.
+// opts 在预设的选项之后应用.
+func NewIdPManager[T jwt.Claims, PT jwtcore.Claims[T]](idp *FakeIdP, expire time.Duration,
+	opts ...jwtcore.Option[T, PT]) *jwtcore.TokenManager[T, PT] {
+	preset := []jwtcore.Option[T, PT]{
+		jwtcore.WithSigner[T, PT](idp.Signer()),
+		jwtcore.WithDecryptKey[T, PT](idp.PublicKeyPEM()),
+		jwtcore.WithIssuer[T, PT](idp.Issuer()),
+	}
+	return jwtcore.NewTokenManager[T, PT]("", expire, append(preset, opts...)...)
+}
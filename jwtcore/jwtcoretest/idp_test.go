@@ -0,0 +1,52 @@
+package jwtcoretest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token/jwtcore"
+)
+
+func TestFakeIdP(t *testing.T) {
+	idp := NewFakeIdP(t)
+	assert.Equal(t, "key-1", idp.KeyID())
+	m := NewIdPManager[jwtcore.IDTokenClaims](idp, 10*time.Minute,
+		jwtcore.WithTimeFunc[jwtcore.IDTokenClaims](func() time.Time { return nowTime }),
+		jwtcore.WithGenAudienceFunc[jwtcore.IDTokenClaims](func() jwt.ClaimStrings {
+			return jwt.ClaimStrings{"client"}
+		}),
+	)
+	verifier := jwtcore.NewOIDCVerifier[jwtcore.IDTokenClaims](idp.Issuer(), "client",
+		jwtcore.WithOIDCHTTPClient(idp.Client()),
+		jwtcore.WithOIDCTimeFunc(func() time.Time { return nowTime }),
+		jwtcore.WithOIDCRefreshInterval(time.Hour, 0),
+	)
+	token, err := m.GenerateToken(jwtcore.IDTokenClaims{})
+	require.NoError(t, err)
+	_, err = verifier.VerifyIDToken(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, idp.JWKSRequests())
+
+	// 轮换密钥后, 旧密钥签发的 token 仍然可以通过校验.
+	idp.RotateKey(t)
+	assert.Equal(t, "key-2", idp.KeyID())
+	rotated := NewIdPManager[jwtcore.IDTokenClaims](idp, 10*time.Minute,
+		jwtcore.WithTimeFunc[jwtcore.IDTokenClaims](func() time.Time { return nowTime }),
+		jwtcore.WithGenAudienceFunc[jwtcore.IDTokenClaims](func() jwt.ClaimStrings {
+			return jwt.ClaimStrings{"client"}
+		}),
+	)
+	newToken, err := rotated.GenerateToken(jwtcore.IDTokenClaims{})
+	require.NoError(t, err)
+	_, err = verifier.VerifyIDToken(context.Background(), newToken, "", "")
+	require.NoError(t, err)
+	_, err = verifier.VerifyIDToken(context.Background(), token, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, idp.JWKSRequests())
+}
@@ -0,0 +1,101 @@
+package jwtcoretest
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/udugong/token/jwtcore"
+)
+
+// TestIssuer 使用 jwt 管理器签发各种用于测试的 token.
+// 签发失败时调用 testing.TB.Fatalf.
+type TestIssuer[T jwt.Claims, PT jwtcore.Claims[T]] struct {
+	tb      testing.TB
+	manager *jwtcore.TokenManager[T, PT]
+	now     func() time.Time
+}
+
+// NewTestIssuer 创建 TestIssuer.
+// now 为签发 token 的时间函数, 通常与被测 jwt 管理器的 WithTimeFunc 相同, 为 nil 时使用 time.Now.
+func NewTestIssuer[T jwt.Claims, PT jwtcore.Claims[T]](tb testing.TB,
+	manager *jwtcore.TokenManager[T, PT], now func() time.Time) *TestIssuer[T, PT] {
+	if now == nil {
+		now = time.Now
+	}
+	return &TestIssuer[T, PT]{tb: tb, manager: manager, now: now}
+}
+
+// Valid 签发有效的 token.
+func (i *TestIssuer[T, PT]) Valid(clm T) string {
+	i.tb.Helper()
+	return i.generate(clm, jwtcore.WithTimeFunc[T, PT](i.now))
+}
+
+// Expired 签发已经过期一小时的 token.
+func (i *TestIssuer[T, PT]) Expired(clm T) string {
+	i.tb.Helper()
+	issuedAt := i.now().Add(-i.manager.Expire - time.Hour)
+	return i.generate(clm, jwtcore.WithTimeFunc[T, PT](func() time.Time { return issuedAt }))
+}
+
+// NotYetValid 签发一小时后才生效 (nbf) 的 token.
+func (i *TestIssuer[T, PT]) NotYetValid(clm T) string {
+	i.tb.Helper()
+	notBefore := i.now().Add(time.Hour)
+	return i.generate(clm,
+		jwtcore.WithTimeFunc[T, PT](i.now),
+		jwtcore.WithGenNotBeforeFunc[T, PT](func() time.Time { return notBefore }),
+	)
+}
+
+// WrongSignature 签发签名被篡改的 token.
+func (i *TestIssuer[T, PT]) WrongSignature(clm T) string {
+	i.tb.Helper()
+	token := i.Valid(clm)
+	dot := strings.LastIndexByte(token, '.')
+	sig, err := base64.RawURLEncoding.DecodeString(token[dot+1:])
+	if err != nil {
+		i.tb.Fatalf("jwtcoretest: 解码签名失败: %v", err)
+	}
+	sig[len(sig)-1] ^= 0xff
+	return token[:dot+1] + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// WrongAlgorithm 签发使用其他签名算法的 token.
+// HMAC 签名时使用相同的密钥与其他的 HMAC 算法;
+// 非对称签名时使用公钥作为 HMAC 密钥, 模拟算法混淆攻击.
+func (i *TestIssuer[T, PT]) WrongAlgorithm(clm T) string {
+	i.tb.Helper()
+	method, key := jwt.SigningMethod(jwt.SigningMethodHS256), i.manager.DecryptKey
+	if _, ok := i.manager.Method.(*jwt.SigningMethodHMAC); ok {
+		method, key = jwt.SigningMethodHS512, i.manager.EncryptionKey
+		if i.manager.Method == jwt.SigningMethodHS512 {
+			method = jwt.SigningMethodHS384
+		}
+	}
+	return i.generate(clm,
+		jwtcore.WithTimeFunc[T, PT](i.now),
+		jwtcore.WithSigner[T, PT](jwtcore.NewKeySigner(method, []byte(key), "")),
+	)
+}
+
+// None 签发 alg 为 none 的未签名 token.
+func (i *TestIssuer[T, PT]) None(clm T) string {
+	i.tb.Helper()
+	token := i.Valid(clm)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	parts := strings.Split(token, ".")
+	return header + "." + parts[1] + "."
+}
+
+func (i *TestIssuer[T, PT]) generate(clm T, opts ...jwtcore.Option[T, PT]) string {
+	i.tb.Helper()
+	token, err := i.manager.WithOptions(opts...).GenerateToken(clm)
+	if err != nil {
+		i.tb.Fatalf("jwtcoretest: 签发 token 失败: %v", err)
+	}
+	return token
+}
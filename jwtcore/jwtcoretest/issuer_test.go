@@ -0,0 +1,58 @@
+package jwtcoretest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/udugong/token/jwtcore"
+)
+
+type MyClaims struct {
+	Uid int64 `json:"uid,omitempty"`
+	jwtcore.RegisteredClaims
+}
+
+const encryptionKey = "sign key"
+
+func TestTestIssuer(t *testing.T) {
+	clock := NewFakeClock(nowTime)
+	managers := map[string]*jwtcore.TokenManager[MyClaims, *MyClaims]{
+		"hs256": jwtcore.NewTokenManager[MyClaims](encryptionKey, 10*time.Minute,
+			jwtcore.WithTimeFunc[MyClaims](clock.Now)),
+		"hs512": jwtcore.NewTokenManager[MyClaims](encryptionKey, 10*time.Minute,
+			jwtcore.WithMethod[MyClaims](jwt.SigningMethodHS512),
+			jwtcore.WithTimeFunc[MyClaims](clock.Now)),
+		"es256": NewIdPManager[MyClaims](NewFakeIdP(t), 10*time.Minute,
+			jwtcore.WithTimeFunc[MyClaims](clock.Now)),
+	}
+	for name, m := range managers {
+		t.Run(name, func(t *testing.T) {
+			issuer := NewTestIssuer(t, m, clock.Now)
+			clm := MyClaims{Uid: 1}
+			got := AssertVerifyOK[MyClaims](t, m, issuer.Valid(clm))
+			assert.Equal(t, int64(1), got.Uid)
+			AssertVerifyErrorIs[MyClaims](t, m, issuer.Expired(clm), jwt.ErrTokenExpired)
+			AssertVerifyErrorIs[MyClaims](t, m, issuer.NotYetValid(clm), jwt.ErrTokenNotValidYet)
+			AssertVerifyErrorIs[MyClaims](t, m, issuer.WrongSignature(clm),
+				jwt.ErrTokenSignatureInvalid)
+			AssertVerifyErrorIs[MyClaims](t, m, issuer.WrongAlgorithm(clm),
+				jwtcore.ErrAlgorithmNotAllowed)
+			AssertVerifyErrorIs[MyClaims](t, m, issuer.None(clm), jwtcore.ErrAlgorithmNotAllowed)
+		})
+	}
+}
+
+func TestTestIssuer_Clock(t *testing.T) {
+	clock := NewFakeClock(nowTime)
+	m := jwtcore.NewTokenManager[MyClaims](encryptionKey, 10*time.Minute,
+		jwtcore.WithTimeFunc[MyClaims](clock.Now))
+	issuer := NewTestIssuer(t, m, clock.Now)
+	token := issuer.Valid(MyClaims{Uid: 1})
+	clock.Advance(10*time.Minute + time.Second)
+	AssertVerifyErrorIs[MyClaims](t, m, token, jwt.ErrTokenExpired)
+	clock.Set(nowTime.Add(time.Minute))
+	AssertVerifyOK[MyClaims](t, m, token)
+}
@@ -0,0 +1,88 @@
+package jwtcore
+
+import (
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// asymmetricAlgorithms 是使用公钥校验签名的算法.
+var asymmetricAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384",
+	"PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// parseSigningKey 根据签名方式解析加密密钥.
+// HMAC 直接使用密钥的字节, RSA/RSA-PSS/ECDSA/EdDSA 需要传入 PEM 编码的私钥.
+func parseSigningKey(method jwt.SigningMethod, key string) (any, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPrivateKeyFromPEM([]byte(key))
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPrivateKeyFromPEM([]byte(key))
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPrivateKeyFromPEM([]byte(key))
+	default:
+		return []byte(key), nil
+	}
+}
+
+// parseVerifyKey 根据签名方式解析解密密钥.
+// HMAC 直接使用密钥的字节, RSA/RSA-PSS/ECDSA/EdDSA 需要传入 PEM 编码的公钥.
+func parseVerifyKey(method jwt.SigningMethod, key string) (any, error) {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(key))
+	case *jwt.SigningMethodECDSA:
+		return jwt.ParseECPublicKeyFromPEM([]byte(key))
+	case *jwt.SigningMethodEd25519:
+		return jwt.ParseEdPublicKeyFromPEM([]byte(key))
+	default:
+		return []byte(key), nil
+	}
+}
+
+// containsAudience 判断 aud 是否包含 cmp.
+func containsAudience(aud jwt.ClaimStrings, cmp string) bool {
+	for _, a := range aud {
+		if a == cmp {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAlgorithm 检查 token 的签名算法是否被允许.
+// 只允许 Method 与 WithAllowedAlgorithms 设置的算法, 始终拒绝 none;
+// 解密密钥为公钥时拒绝 HMAC, 防止以公钥作为 HMAC 密钥伪造 token.
+func (t *TokenManager[T, PT]) checkAlgorithm(method jwt.SigningMethod) error {
+	alg := method.Alg()
+	if alg == jwt.SigningMethodNone.Alg() || !t.algorithmAllowed(alg) {
+		return fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, alg)
+	}
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		if _, ok = t.Method.(*jwt.SigningMethodHMAC); !ok || isPublicKeyPEM(t.DecryptKey) {
+			return fmt.Errorf("%w: 不能使用公钥进行 HMAC 校验", ErrAlgorithmNotAllowed)
+		}
+	}
+	return nil
+}
+
+func (t *TokenManager[T, PT]) algorithmAllowed(alg string) bool {
+	if alg == t.Method.Alg() {
+		return true
+	}
+	for _, allowed := range t.allowedAlgorithms {
+		if alg == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublicKeyPEM 判断密钥是否为 PEM 编码的公钥或证书.
+func isPublicKeyPEM(key string) bool {
+	block, _ := pem.Decode([]byte(key))
+	return block != nil && (strings.Contains(block.Type, "PUBLIC KEY") ||
+		block.Type == "CERTIFICATE")
+}
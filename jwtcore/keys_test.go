@@ -0,0 +1,193 @@
+package jwtcore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenManager_AsymmetricMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     jwt.SigningMethod
+		privateKey string
+		publicKey  string
+	}{
+		{
+			name:       "rs256",
+			method:     jwt.SigningMethodRS256,
+			privateKey: rsaPrivateKeyPEM,
+			publicKey:  rsaPublicKeyPEM,
+		},
+		{
+			name:       "ps256",
+			method:     jwt.SigningMethodPS256,
+			privateKey: rsaPrivateKeyPEM,
+			publicKey:  rsaPublicKeyPEM,
+		},
+		{
+			name:       "es256",
+			method:     jwt.SigningMethodES256,
+			privateKey: ecPrivateKeyPEM,
+			publicKey:  ecPublicKeyPEM,
+		},
+		{
+			name:       "eddsa",
+			method:     jwt.SigningMethodEdDSA,
+			privateKey: edPrivateKeyPEM,
+			publicKey:  edPublicKeyPEM,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewTokenManager[MyClaims](tt.privateKey, defaultExpire,
+				WithMethod[MyClaims](tt.method),
+				WithDecryptKey[MyClaims](tt.publicKey),
+			)
+			token, err := m.GenerateToken(MyClaims{Uid: 1})
+			require.NoError(t, err)
+			got, err := m.VerifyToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestTokenManager_BadPEMKey(t *testing.T) {
+	m := NewTokenManager[MyClaims]("not a pem", defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodRS256),
+	)
+	_, err := m.GenerateToken(MyClaims{Uid: 1})
+	assert.ErrorIs(t, err, jwt.ErrKeyMustBePEMEncoded)
+}
+
+func TestTokenManager_AlgorithmNotAllowed(t *testing.T) {
+	// signToken 使用任意签名方式与密钥签发 token.
+	signToken := func(method jwt.SigningMethod, key any) string {
+		token, err := jwt.NewWithClaims(method, MyClaims{Uid: 1}).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	rsaManager := NewTokenManager[MyClaims](rsaPrivateKeyPEM, defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodRS256),
+		WithDecryptKey[MyClaims](rsaPublicKeyPEM),
+	)
+	tests := []struct {
+		name    string
+		m       *TokenManager[MyClaims, *MyClaims]
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "default_method",
+			m:     rsaManager,
+			token: signToken(jwt.SigningMethodRS256, rsaPrivateKey),
+		},
+		{
+			name:    "none",
+			m:       rsaManager,
+			token:   signToken(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name: "none_allowed_explicitly",
+			m: rsaManager.WithOptions(
+				WithAllowedAlgorithms[MyClaims](jwt.SigningMethodNone.Alg())),
+			token:   signToken(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:    "not_in_allowlist",
+			m:       rsaManager,
+			token:   signToken(jwt.SigningMethodPS256, rsaPrivateKey),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name: "in_allowlist",
+			m: rsaManager.WithOptions(
+				WithAllowedAlgorithms[MyClaims](jwt.SigningMethodPS256.Alg())),
+			token: signToken(jwt.SigningMethodPS256, rsaPrivateKey),
+		},
+		{
+			// 使用公钥作为 HMAC 密钥伪造 token
+			name: "hmac_with_public_key",
+			m: rsaManager.WithOptions(
+				WithAllowedAlgorithms[MyClaims](jwt.SigningMethodHS256.Alg())),
+			token:   signToken(jwt.SigningMethodHS256, []byte(rsaPublicKeyPEM)),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name: "hmac_method_with_public_key",
+			m: NewTokenManager[MyClaims](rsaPublicKeyPEM, defaultExpire,
+				WithDecryptKey[MyClaims](rsaPublicKeyPEM)),
+			token:   signToken(jwt.SigningMethodHS256, []byte(rsaPublicKeyPEM)),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:  "hmac",
+			m:     NewTokenManager[MyClaims](encryptionKey, defaultExpire),
+			token: signToken(jwt.SigningMethodHS256, []byte(encryptionKey)),
+		},
+		{
+			name:    "hmac_not_in_allowlist",
+			m:       NewTokenManager[MyClaims](encryptionKey, defaultExpire),
+			token:   signToken(jwt.SigningMethodHS512, []byte(encryptionKey)),
+			wantErr: ErrAlgorithmNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.VerifyToken(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+// encodePEM 将密钥编码为 PEM.
+func encodePEM(typ string, der []byte) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func mustMarshalPKCS8(key crypto.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+func mustMarshalPKIX(key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+var (
+	rsaPrivateKey, _             = rsa.GenerateKey(rand.Reader, 2048)
+	ecPrivateKey, _              = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, edPrivateKey, _ = ed25519.GenerateKey(rand.Reader)
+
+	rsaPrivateKeyPEM = encodePEM("PRIVATE KEY", mustMarshalPKCS8(rsaPrivateKey))
+	rsaPublicKeyPEM  = encodePEM("PUBLIC KEY", mustMarshalPKIX(&rsaPrivateKey.PublicKey))
+	ecPrivateKeyPEM  = encodePEM("PRIVATE KEY", mustMarshalPKCS8(ecPrivateKey))
+	ecPublicKeyPEM   = encodePEM("PUBLIC KEY", mustMarshalPKIX(&ecPrivateKey.PublicKey))
+	edPrivateKeyPEM  = encodePEM("PRIVATE KEY", mustMarshalPKCS8(edPrivateKey))
+	edPublicKeyPEM   = encodePEM("PUBLIC KEY", mustMarshalPKIX(edPublicKey))
+)
@@ -0,0 +1,71 @@
+package jwtcore
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// checkLimits 在解析 token 前检查 token 的长度与 JSON 的嵌套深度,
+// 以较小的代价拒绝超限的 token. 返回的错误包含 jwt.ErrTokenMalformed.
+func (t *TokenManager[T, PT]) checkLimits(token string) error {
+	return checkTokenLimits(token, t.maxTokenSize, t.maxJSONDepth)
+}
+
+// checkTokenLimits 检查 token 的长度与 JSON 的嵌套深度, 为 0 的限制不检查.
+func checkTokenLimits(token string, maxSize, maxDepth int) error {
+	if maxSize > 0 && len(token) > maxSize {
+		return fmt.Errorf("%w: %w: %d > %d", jwt.ErrTokenMalformed, ErrTokenTooLarge,
+			len(token), maxSize)
+	}
+	if maxDepth <= 0 {
+		return nil
+	}
+	// 只检查头部与 payload, 无法解码的部分交给 jwt 解析器报告错误.
+	segments := strings.SplitN(token, ".", 3)
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	for _, seg := range segments {
+		b, err := base64.RawURLEncoding.DecodeString(seg)
+		if err != nil {
+			continue
+		}
+		if jsonDepthExceeds(b, maxDepth) {
+			return fmt.Errorf("%w: %w: > %d", jwt.ErrTokenMalformed, ErrTokenTooDeep, maxDepth)
+		}
+	}
+	return nil
+}
+
+// jsonDepthExceeds 报告 JSON 对象与数组的嵌套深度是否超过 maxDepth.
+// 只扫描括号, 不校验 JSON 的语法.
+func jsonDepthExceeds(b []byte, maxDepth int) bool {
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range b {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > maxDepth {
+				return true
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return false
+}
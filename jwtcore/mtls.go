@@ -0,0 +1,93 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// BearerScheme 是 Authorization 请求头中 Bearer token 的认证方案.
+const BearerScheme = "Bearer"
+
+// CertificateThumbprint 计算证书的 SHA-256 指纹 (x5t#S256).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return encodeBase64(sum[:])
+}
+
+// MTLS 签发与校验绑定客户端证书的 token.
+// See https://datatracker.ietf.org/doc/html/rfc8705#section-3
+type MTLS[T jwt.Claims, PT BoundClaims[T]] struct {
+	manager *TokenManager[T, PT]
+}
+
+// NewMTLS 使用 jwt 管理器创建 MTLS.
+func NewMTLS[T jwt.Claims, PT BoundClaims[T]](manager *TokenManager[T, PT]) *MTLS[T, PT] {
+	return &MTLS[T, PT]{manager: manager}
+}
+
+// GenerateToken 生成绑定客户端证书的 token.
+// token 的 cnf.x5t#S256 为证书的 SHA-256 指纹.
+func (m *MTLS[T, PT]) GenerateToken(clm T, cert *x509.Certificate) (string, error) {
+	return m.GenerateTokenContext(context.Background(), clm, cert)
+}
+
+// GenerateTokenContext 与 GenerateToken 相同, ctx 会传递给 jwt 管理器.
+func (m *MTLS[T, PT]) GenerateTokenContext(ctx context.Context, clm T,
+	cert *x509.Certificate) (string, error) {
+	PT(&clm).SetConfirmation(&Confirmation{X509Thumbprint: CertificateThumbprint(cert)})
+	return m.manager.GenerateTokenContext(ctx, clm)
+}
+
+// VerifyRequest 校验请求中的 Bearer token 是否绑定了 TLS 连接的客户端证书,
+// 并返回 claims 与 error.
+// jwt 管理器设置了 WithAuditLogger 时, 证书不匹配等失败也会记录审计日志.
+func (m *MTLS[T, PT]) VerifyRequest(r *http.Request) (T, error) {
+	var zeroClm T
+	token, ok := tokenFromHeader(r, BearerScheme)
+	if !ok {
+		return zeroClm, ErrTokenNotFound
+	}
+	clm, err := m.manager.VerifyTokenContext(withBindingCheck(r.Context()), token)
+	if err != nil {
+		return zeroClm, err
+	}
+	if err = m.verifyBinding(r, PT(&clm)); err != nil {
+		m.manager.auditRequest(r.Context(), token, err)
+		return zeroClm, err
+	}
+	return clm, nil
+}
+
+// verifyBinding 校验 token 是否绑定了 TLS 连接的客户端证书.
+func (m *MTLS[T, PT]) verifyBinding(r *http.Request, clm PT) error {
+	cnf := clm.GetConfirmation()
+	if cnf == nil || cnf.X509Thumbprint == "" {
+		return ErrTokenNotBound
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: 缺少客户端证书", ErrTokenNotBound)
+	}
+	if CertificateThumbprint(r.TLS.PeerCertificates[0]) != cnf.X509Thumbprint {
+		return ErrTokenNotBound
+	}
+	return nil
+}
+
+// Middleware 返回校验证书绑定 token 的中间件.
+// 校验通过的 claims 可以通过 ClaimsFromContext 获取.
+func (m *MTLS[T, PT]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clm, err := m.VerifyRequest(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", BearerScheme+` error="invalid_token"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), clm)))
+	})
+}
@@ -0,0 +1,177 @@
+package jwtcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCertificate 生成测试证书, parent 为 nil 时生成自签名的 CA 证书.
+func newTestCertificate(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	parentCert, parentKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		parentCert, parentKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestMTLS_Middleware(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", &ca)
+	anotherCert := newTestCertificate(t, "another", &ca)
+
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire)
+	mtls := NewMTLS(m)
+	boundToken, err := mtls.GenerateToken(BoundMyClaims{Uid: 1}, clientCert.Leaf)
+	require.NoError(t, err)
+	unboundToken, err := m.GenerateToken(BoundMyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	srv := httptest.NewUnstartedServer(mtls.Middleware(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			clm, ok := ClaimsFromContext[BoundMyClaims](r.Context())
+			assert.True(t, ok)
+			assert.Equal(t, int64(1), clm.Uid)
+			w.WriteHeader(http.StatusNoContent)
+		})))
+	srv.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		cert       *tls.Certificate
+		auth       string
+		wantStatus int
+	}{
+		{
+			name:       "normal",
+			cert:       &clientCert,
+			auth:       "Bearer " + boundToken,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			// token 被盗用, 但没有对应的证书
+			name:       "another_certificate",
+			cert:       &anotherCert,
+			auth:       "Bearer " + boundToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "without_certificate",
+			auth:       "Bearer " + boundToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unbound_token",
+			cert:       &clientCert,
+			auth:       "Bearer " + unboundToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing_token",
+			cert:       &clientCert,
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := srv.Client()
+			transport := client.Transport.(*http.Transport).Clone()
+			if tt.cert != nil {
+				transport.TLSClientConfig.Certificates = []tls.Certificate{*tt.cert}
+			}
+			client.Transport = transport
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			require.NoError(t, err)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer error="invalid_token"`, resp.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestMTLS_VerifyRequest(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", &ca)
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire)
+	mtls := NewMTLS(m)
+	token, err := mtls.GenerateToken(BoundMyClaims{Uid: 1}, clientCert.Leaf)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	_, err = mtls.VerifyRequest(r)
+	assert.ErrorIs(t, err, ErrTokenNotBound)
+
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert.Leaf}}
+	got, err := mtls.VerifyRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, CertificateThumbprint(clientCert.Leaf), got.Confirmation.X509Thumbprint)
+}
+
+func TestMTLS_BoundTokenAsBearer(t *testing.T) {
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", &ca)
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire)
+	token, err := NewMTLS(m).GenerateToken(BoundMyClaims{Uid: 1}, clientCert.Leaf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		manager *TokenManager[BoundMyClaims, *BoundMyClaims]
+	}{
+		{name: "plain", manager: m},
+		{name: "observed", manager: m.WithOptions(WithObserver[BoundMyClaims](ObserverFuncs{}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 绑定证书的 token 不能作为普通 Bearer token 使用
+			clm, err := tt.manager.VerifyToken(token)
+			assert.ErrorIs(t, err, ErrTokenNotBound)
+			assert.Equal(t, CategoryBinding, ErrorCategoryOf(err))
+			assert.Zero(t, clm)
+		})
+	}
+}
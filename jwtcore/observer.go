@@ -0,0 +1,225 @@
+package jwtcore
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/udugong/token"
+)
+
+// Observer 观察 token 的签发与校验, 用于采集指标.
+// Prometheus、OpenTelemetry 等指标系统可以实现此接口, 方法会在 GenerateToken
+// 与 VerifyToken 的调用方 goroutine 中同步调用, 实现应该尽快返回并且可以并发使用.
+type Observer interface {
+	OnGenerate(GenerateEvent)
+	OnVerify(VerifyEvent)
+}
+
+// GenerateEvent 是一次签发的结果.
+type GenerateEvent struct {
+	Algorithm string
+	KeyID     string
+	Duration  time.Duration
+	Err       error
+	Category  ErrorCategory // Err 的类别, 成功时为空
+}
+
+// VerifyEvent 是一次校验的结果.
+// token 格式错误时 Algorithm 与 KeyID 可能为空.
+type VerifyEvent struct {
+	Algorithm string
+	KeyID     string
+	Duration  time.Duration
+	Err       error
+	Category  ErrorCategory // Err 的类别, 成功时为空
+}
+
+// ObserverFuncs 使用函数实现 Observer, 为 nil 的函数会被忽略.
+type ObserverFuncs struct {
+	Generate func(GenerateEvent)
+	Verify   func(VerifyEvent)
+}
+
+func (o ObserverFuncs) OnGenerate(e GenerateEvent) {
+	if o.Generate != nil {
+		o.Generate(e)
+	}
+}
+
+func (o ObserverFuncs) OnVerify(e VerifyEvent) {
+	if o.Verify != nil {
+		o.Verify(e)
+	}
+}
+
+// ErrorCategory 是错误的类别, 可以作为指标的标签.
+type ErrorCategory string
+
+const (
+	CategoryMalformed    ErrorCategory = "malformed"     // token 格式错误
+	CategoryAlgorithm    ErrorCategory = "algorithm"     // 签名算法不被允许
+	CategorySignature    ErrorCategory = "signature"     // 签名无效
+	CategoryExpired      ErrorCategory = "expired"       // token 已过期
+	CategoryNotValidYet  ErrorCategory = "not_valid_yet" // token 尚未生效
+	CategoryClaims       ErrorCategory = "claims"        // 其他 claims 校验失败
+	CategoryRevoked      ErrorCategory = "revoked"       // token 已被吊销
+	CategoryBinding      ErrorCategory = "binding"       // 客户端证书或 DPoP proof 不匹配、重放
+	CategoryUnverifiable ErrorCategory = "unverifiable"  // 无法获取校验签名的密钥
+	CategorySign         ErrorCategory = "sign"          // 签名失败
+	CategoryCanceled     ErrorCategory = "canceled"      // context 已结束
+	CategoryOther        ErrorCategory = "other"         // 其他错误
+)
+
+// ErrorCategoryOf 返回 err 的类别, err 为 nil 时返回空字符串.
+func ErrorCategoryOf(err error) ErrorCategory {
+	var validationErr *ValidationError
+	var signErr *SignError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return CategoryCanceled
+	case errors.Is(err, ErrTokenRevoked):
+		return CategoryRevoked
+	case errors.Is(err, ErrTokenNotBound), errors.Is(err, ErrInvalidDPoPProof), errors.Is(err, ErrReplayDetected):
+		return CategoryBinding
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return CategoryMalformed
+	case errors.Is(err, ErrAlgorithmNotAllowed):
+		return CategoryAlgorithm
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return CategorySignature
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return CategoryExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return CategoryNotValidYet
+	case errors.Is(err, jwt.ErrTokenInvalidClaims), errors.As(err, &validationErr):
+		return CategoryClaims
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		return CategoryUnverifiable
+	case errors.As(err, &signErr):
+		return CategorySign
+	default:
+		return CategoryOther
+	}
+}
+
+// observeGenerate 生成 token, 通知 Observer、记录审计日志并结束 span.
+func (t *TokenManager[T, PT]) observeGenerate(ctx context.Context, clm T) (string, error) {
+	ctx, span := t.startSpan(ctx, token.SpanGenerateToken)
+	start := time.Now()
+	t.fillClaims(PT(&clm))
+	tokenString, err := t.signClaims(ctx, clm)
+	duration := time.Since(start)
+	alg, kid := t.signerInfo()
+	if t.observer != nil {
+		t.observer.OnGenerate(GenerateEvent{
+			Algorithm: alg,
+			KeyID:     kid,
+			Duration:  duration,
+			Err:       err,
+			Category:  ErrorCategoryOf(err),
+		})
+	}
+	if t.auditLogger != nil {
+		auditIssued(ctx, t.auditLogger, alg, kid, clm, err)
+	}
+	endSpan(span, alg, kid, t.Issuer, err)
+	return tokenString, err
+}
+
+// signerInfo 返回签发 token 使用的签名算法与 kid.
+func (t *TokenManager[T, PT]) signerInfo() (alg, kid string) {
+	signer := t.tokenSigner()
+	alg, kid = signer.Algorithm(), signer.KeyID()
+	if kid == "" {
+		kid, _ = t.headers["kid"].(string)
+	}
+	return alg, kid
+}
+
+// observeVerify 校验 token, 通知 Observer、记录审计日志并结束 span.
+func (t *TokenManager[T, PT]) observeVerify(ctx context.Context, tokenString string) (T, error) {
+	ctx, span := t.startSpan(ctx, token.SpanVerifyToken)
+	start := time.Now()
+	var clm T
+	var info tokenInfo
+	err := t.verify(tokenString, &clm, &info)
+	if err == nil {
+		err = checkBinding(ctx, PT(&clm))
+	}
+	duration := time.Since(start)
+	if t.observer != nil {
+		t.observer.OnVerify(VerifyEvent{
+			Algorithm: info.alg,
+			KeyID:     info.kid,
+			Duration:  duration,
+			Err:       err,
+			Category:  ErrorCategoryOf(err),
+		})
+	}
+	if t.auditLogger != nil {
+		auditVerified(ctx, t.auditLogger, tokenString, info.alg, info.kid, clm, err)
+	}
+	if err != nil {
+		endSpan(span, info.alg, info.kid, "", err)
+		var zeroClm T
+		return zeroClm, err
+	}
+	// 校验通过后 iss 才可信
+	issuer, _ := PT(&clm).GetIssuer()
+	endSpan(span, info.alg, info.kid, issuer, nil)
+	return clm, nil
+}
+
+// ExpvarObserver 使用 expvar 记录签发与校验的指标:
+//
+//	generate_total, generate_errors     签发的次数与失败的次数
+//	verify_total, verify_errors         校验的次数与失败的次数
+//	verify_errors_by_category           按 ErrorCategory 统计的校验失败次数
+//	verify_by_algorithm                 按签名算法统计的校验次数
+//	generate_duration_ns, verify_duration_ns  累计耗时 (纳秒)
+type ExpvarObserver struct {
+	m          *expvar.Map
+	byAlg      *expvar.Map
+	byCategory *expvar.Map
+}
+
+// NewExpvarObserver 创建 ExpvarObserver, 指标记录在 m 中.
+// 通常使用 expvar.NewMap("jwtcore") 发布到 /debug/vars.
+func NewExpvarObserver(m *expvar.Map) *ExpvarObserver {
+	o := &ExpvarObserver{
+		m:          m,
+		byAlg:      new(expvar.Map).Init(),
+		byCategory: new(expvar.Map).Init(),
+	}
+	m.Set("verify_by_algorithm", o.byAlg)
+	m.Set("verify_errors_by_category", o.byCategory)
+	return o
+}
+
+func (o *ExpvarObserver) OnGenerate(e GenerateEvent) {
+	o.m.Add("generate_total", 1)
+	o.m.Add("generate_duration_ns", int64(e.Duration))
+	if e.Err != nil {
+		o.m.Add("generate_errors", 1)
+	}
+}
+
+func (o *ExpvarObserver) OnVerify(e VerifyEvent) {
+	o.m.Add("verify_total", 1)
+	o.m.Add("verify_duration_ns", int64(e.Duration))
+	alg := e.Algorithm
+	if alg == "" {
+		alg = "unknown"
+	}
+	o.byAlg.Add(alg, 1)
+	if e.Err != nil {
+		o.m.Add("verify_errors", 1)
+		o.byCategory.Add(string(e.Category), 1)
+	}
+}
@@ -0,0 +1,102 @@
+package jwtcore
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCategoryOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorCategory
+	}{
+		{err: nil, want: ""},
+		{err: fmt.Errorf("验证失败: %w", context.DeadlineExceeded), want: CategoryCanceled},
+		{err: fmt.Errorf("验证失败: %w", ErrTokenRevoked), want: CategoryRevoked},
+		{err: fmt.Errorf("%w: %w", ErrInvalidDPoPProof, ErrReplayDetected), want: CategoryBinding},
+		{err: jwtError("", jwt.ErrTokenMalformed), want: CategoryMalformed},
+		{err: jwtError("", jwt.ErrTokenUnverifiable, ErrAlgorithmNotAllowed), want: CategoryAlgorithm},
+		{err: jwtError("", jwt.ErrTokenSignatureInvalid), want: CategorySignature},
+		{err: jwtError("", jwt.ErrTokenInvalidClaims, jwt.ErrTokenExpired), want: CategoryExpired},
+		{err: jwtError("", jwt.ErrTokenInvalidClaims, jwt.ErrTokenNotValidYet), want: CategoryNotValidYet},
+		{err: jwtError("", jwt.ErrTokenInvalidClaims, jwt.ErrTokenInvalidIssuer), want: CategoryClaims},
+		{err: &ValidationError{Errors: []error{&ClaimError{Claim: "sub", Err: ErrClaimMissing}}}, want: CategoryClaims},
+		{err: jwtError("", jwt.ErrTokenUnverifiable, ErrKeyNotFound), want: CategoryUnverifiable},
+		{err: &SignError{Err: ErrKMSUnavailable}, want: CategorySign},
+		{err: errors.New("boom"), want: CategoryOther},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.want), func(t *testing.T) {
+			assert.Equal(t, tt.want, ErrorCategoryOf(tt.err))
+		})
+	}
+}
+
+func TestTokenManager_Observer(t *testing.T) {
+	var generated []GenerateEvent
+	var verified []VerifyEvent
+	o := ObserverFuncs{
+		Generate: func(e GenerateEvent) { generated = append(generated, e) },
+		Verify:   func(e VerifyEvent) { verified = append(verified, e) },
+	}
+	now := func() time.Time { return nowTime }
+	managers := map[string]*TokenManager[MyClaims, *MyClaims]{
+		"fast": NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+			WithTimeFunc[MyClaims](now), WithKeyID[MyClaims]("k1"), WithObserver[MyClaims](o)),
+		"parser": defaultManager.WithOptions(WithKeyID[MyClaims]("k1"), WithObserver[MyClaims](o)),
+	}
+	for name, m := range managers {
+		t.Run(name, func(t *testing.T) {
+			generated, verified = nil, nil
+			token, err := m.GenerateToken(MyClaims{Uid: 1})
+			require.NoError(t, err)
+			require.Len(t, generated, 1)
+			assert.Equal(t, "HS256", generated[0].Algorithm)
+			assert.Equal(t, "k1", generated[0].KeyID)
+			assert.NoError(t, generated[0].Err)
+
+			got, err := m.VerifyToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+			_, err = m.VerifyToken(token[:len(token)-2] + "AA")
+			require.Error(t, err)
+			_, err = m.VerifyToken("bad_token")
+			require.Error(t, err)
+
+			require.Len(t, verified, 3)
+			assert.Equal(t, VerifyEvent{Algorithm: "HS256", KeyID: "k1", Duration: verified[0].Duration},
+				verified[0])
+			assert.Equal(t, "k1", verified[1].KeyID)
+			assert.Equal(t, CategorySignature, verified[1].Category)
+			assert.Equal(t, CategoryMalformed, verified[2].Category)
+			assert.Equal(t, "", verified[2].Algorithm)
+		})
+	}
+}
+
+func TestExpvarObserver(t *testing.T) {
+	vars := new(expvar.Map).Init()
+	m := defaultManager.WithOptions(WithObserver[MyClaims](NewExpvarObserver(vars)))
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = m.VerifyToken(token)
+	require.NoError(t, err)
+	_, _ = m.VerifyToken("bad_token")
+	_, _ = m.VerifyToken(token[:len(token)-2] + "AA")
+
+	assert.Equal(t, "1", vars.Get("generate_total").String())
+	assert.Nil(t, vars.Get("generate_errors"))
+	assert.Equal(t, "3", vars.Get("verify_total").String())
+	assert.Equal(t, "2", vars.Get("verify_errors").String())
+	assert.JSONEq(t, `{"HS256": 2, "unknown": 1}`, vars.Get("verify_by_algorithm").String())
+	assert.JSONEq(t, `{"malformed": 1, "signature": 1}`,
+		vars.Get("verify_errors_by_category").String())
+}
@@ -0,0 +1,378 @@
+package jwtcore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCVerifier 校验第三方 OpenID Provider 签发的 ID token.
+// 配置文档与 JWKS 在首次校验时获取并缓存, 缓存过期或遇到未知的 kid 时刷新 JWKS,
+// 刷新失败时继续使用已缓存的 JWKS.
+type OIDCVerifier[T jwt.Claims, PT IDClaims[T]] struct {
+	issuer   string
+	clientID string
+	oidcConfig
+
+	mu               sync.Mutex
+	metadata         *ProviderMetadata
+	metadataErr      error     // 上一次获取配置文档失败的原因
+	metadataFailedAt time.Time // 上一次获取配置文档失败的时间
+	metadataFlight   *flight[*ProviderMetadata]
+	keys             JSONWebKeySet
+	fetchedAt        time.Time
+	keysErr          error     // 上一次获取 JWKS 失败的原因
+	keysFailedAt     time.Time // 上一次获取 JWKS 失败的时间
+	keysFlight       *flight[JSONWebKeySet]
+}
+
+// flight 是进行中的请求, 并发的调用方共享它的结果.
+type flight[R any] struct {
+	done     chan struct{}
+	val      R
+	err      error
+	canceled bool // 发起请求的 ctx 已结束, 结果不应共享
+}
+
+// share 在不持有 mu 的情况下执行 fetch, 请求进行中时等待 *slot 的结果.
+// 调用时必须持有 mu, 返回时已释放 mu. store 在持有 mu 时保存 fetch 的结果.
+// 返回的 ok 为 false 表示发起请求的 ctx 已结束, 调用方应该重试.
+func share[R any](ctx context.Context, mu *sync.Mutex, slot **flight[R],
+	fetch func(context.Context) (R, error), store func(R, error, bool)) (val R, ok bool, err error) {
+	if f := *slot; f != nil {
+		mu.Unlock()
+		select {
+		case <-f.done:
+			return f.val, !f.canceled, f.err
+		case <-ctx.Done():
+			return val, true, ctx.Err()
+		}
+	}
+	f := &flight[R]{done: make(chan struct{})}
+	*slot = f
+	mu.Unlock()
+
+	f.val, f.err = fetch(ctx)
+	f.canceled = f.err != nil && ctx.Err() != nil
+	mu.Lock()
+	*slot = nil
+	store(f.val, f.err, f.canceled)
+	mu.Unlock()
+	close(f.done)
+	return f.val, true, f.err
+}
+
+type oidcConfig struct {
+	client             *http.Client
+	leeway             time.Duration    // exp/iat/nbf 的容差
+	refreshInterval    time.Duration    // JWKS 的缓存时间
+	minRefreshInterval time.Duration    // 刷新 JWKS 与重新获取配置文档的最小间隔
+	timeFunc           func() time.Time // 控制校验的时间
+	algorithms         []string         // 允许的签名算法
+}
+
+// An OIDCOption configures an OIDCVerifier.
+type OIDCOption interface {
+	apply(*oidcConfig)
+}
+
+// oidcOptionFunc wraps a func, so it satisfies the OIDCOption interface.
+type oidcOptionFunc func(*oidcConfig)
+
+func (f oidcOptionFunc) apply(c *oidcConfig) {
+	f(c)
+}
+
+// WithOIDCHTTPClient 设置获取配置文档与 JWKS 的 http.Client.
+func WithOIDCHTTPClient(client *http.Client) OIDCOption {
+	return oidcOptionFunc(func(c *oidcConfig) {
+		c.client = client
+	})
+}
+
+// WithOIDCLeeway 设置校验 exp/iat/nbf 的容差.
+func WithOIDCLeeway(leeway time.Duration) OIDCOption {
+	return oidcOptionFunc(func(c *oidcConfig) {
+		c.leeway = leeway
+	})
+}
+
+// WithOIDCTimeFunc 设置校验的时间函数.
+func WithOIDCTimeFunc(fn func() time.Time) OIDCOption {
+	return oidcOptionFunc(func(c *oidcConfig) {
+		c.timeFunc = fn
+	})
+}
+
+// WithOIDCRefreshInterval 设置 JWKS 的缓存时间,
+// 以及遇到未知 kid 时刷新 JWKS、重新获取失败的配置文档的最小间隔.
+func WithOIDCRefreshInterval(interval, minInterval time.Duration) OIDCOption {
+	return oidcOptionFunc(func(c *oidcConfig) {
+		c.refreshInterval = interval
+		c.minRefreshInterval = minInterval
+	})
+}
+
+// WithOIDCAlgorithms 设置允许的签名算法.
+// 默认使用配置文档中的 id_token_signing_alg_values_supported.
+func WithOIDCAlgorithms(algs ...string) OIDCOption {
+	return oidcOptionFunc(func(c *oidcConfig) {
+		c.algorithms = algs
+	})
+}
+
+// NewOIDCVerifier 创建 ID token 校验器.
+// issuer: OpenID Provider 的 issuer URL, 配置文档从 issuer + DiscoveryPath 获取.
+// clientID: 依赖方的 client ID, ID token 的 aud 必须包含它.
+func NewOIDCVerifier[T jwt.Claims, PT IDClaims[T]](issuer, clientID string,
+	opts ...OIDCOption) *OIDCVerifier[T, PT] {
+	v := &OIDCVerifier[T, PT]{
+		issuer:   issuer,
+		clientID: clientID,
+		oidcConfig: oidcConfig{
+			client:             http.DefaultClient,
+			refreshInterval:    time.Hour,
+			minRefreshInterval: time.Minute,
+			timeFunc:           time.Now,
+		},
+	}
+	for _, opt := range opts {
+		opt.apply(&v.oidcConfig)
+	}
+	return v
+}
+
+// VerifyIDToken 校验 ID token 并返回 claims 与 error.
+// nonce 不为空时校验 nonce, accessToken 不为空时校验 at_hash.
+func (v *OIDCVerifier[T, PT]) VerifyIDToken(ctx context.Context,
+	token, nonce, accessToken string) (T, error) {
+	var zeroClm T
+	metadata, err := v.providerMetadata(ctx)
+	if err != nil {
+		return zeroClm, fmt.Errorf("验证失败: %w", err)
+	}
+	clm := zeroClm
+	withClaims, err := jwt.ParseWithClaims(token, PT(&clm),
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return v.verificationKeys(ctx, kid)
+		},
+		jwt.WithValidMethods(v.validMethods(metadata)),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.clientID),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.timeFunc),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !withClaims.Valid {
+		return zeroClm, fmt.Errorf("验证失败: %w", err)
+	}
+	if err = v.verifyIDClaims(PT(&clm), withClaims.Method.Alg(),
+		nonce, accessToken); err != nil {
+		return zeroClm, fmt.Errorf("验证失败: %w", err)
+	}
+	return clm, nil
+}
+
+// verifyIDClaims 校验 iat, azp, nonce 与 at_hash.
+// jwt.WithIssuedAt 只拒绝晚于当前时间的 iat, ID token 必须包含 iat.
+// See https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
+func (v *OIDCVerifier[T, PT]) verifyIDClaims(clm PT, alg, nonce, accessToken string) error {
+	if iat, err := clm.GetIssuedAt(); err != nil || iat == nil {
+		return fmt.Errorf("%w: iat", jwt.ErrTokenRequiredClaimMissing)
+	}
+	aud, _ := clm.GetAudience()
+	azp := clm.GetAuthorizedParty()
+	if (len(aud) > 1 || azp != "") && azp != v.clientID {
+		return ErrAuthorizedPartyMismatch
+	}
+	if nonce != "" && clm.GetNonce() != nonce {
+		return ErrNonceMismatch
+	}
+	if accessToken != "" {
+		hash, err := TokenHash(alg, accessToken)
+		if err != nil {
+			return err
+		}
+		if clm.GetAccessTokenHash() != hash {
+			return ErrAccessTokenHashMismatch
+		}
+	}
+	return nil
+}
+
+// validMethods 返回允许的签名算法.
+// ID token 的密钥来自 JWKS, 因此排除 none 与 HMAC 算法.
+func (v *OIDCVerifier[T, PT]) validMethods(metadata *ProviderMetadata) []string {
+	if len(v.algorithms) > 0 {
+		return v.algorithms
+	}
+	var algs []string
+	for _, alg := range metadata.IDTokenSigningAlgValuesSupported {
+		if alg == jwt.SigningMethodNone.Alg() || strings.HasPrefix(alg, "HS") {
+			continue
+		}
+		algs = append(algs, alg)
+	}
+	if len(algs) == 0 {
+		// OpenID Provider 必须支持 RS256.
+		algs = []string{jwt.SigningMethodRS256.Alg()}
+	}
+	return algs
+}
+
+// verificationKeys 返回用于校验签名的公钥.
+// kid 为空时返回全部公钥; 找不到 kid 时刷新 JWKS 后再查找一次.
+func (v *OIDCVerifier[T, PT]) verificationKeys(ctx context.Context,
+	kid string) (jwt.VerificationKeySet, error) {
+	keys, err := v.keySet(ctx, false)
+	if err != nil {
+		return jwt.VerificationKeySet{}, err
+	}
+	candidates := keys.Keys
+	if kid != "" {
+		candidates = keys.LookupKeyID(kid)
+		if len(candidates) == 0 {
+			if keys, err = v.keySet(ctx, true); err != nil {
+				return jwt.VerificationKeySet{}, err
+			}
+			candidates = keys.LookupKeyID(kid)
+		}
+	}
+	var set jwt.VerificationKeySet
+	for _, k := range candidates {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, pub)
+	}
+	if len(set.Keys) == 0 {
+		return set, ErrKeyNotFound
+	}
+	return set, nil
+}
+
+// providerMetadata 获取并缓存配置文档.
+// 获取失败时, 在 minRefreshInterval 内返回相同的错误, 不再请求.
+func (v *OIDCVerifier[T, PT]) providerMetadata(ctx context.Context) (*ProviderMetadata, error) {
+	for {
+		v.mu.Lock()
+		if v.metadata != nil {
+			metadata := v.metadata
+			v.mu.Unlock()
+			return metadata, nil
+		}
+		if v.metadataErr != nil && v.timeFunc().Sub(v.metadataFailedAt) < v.minRefreshInterval {
+			err := v.metadataErr
+			v.mu.Unlock()
+			return nil, err
+		}
+		metadata, ok, err := share(ctx, &v.mu, &v.metadataFlight, v.fetchMetadata,
+			func(metadata *ProviderMetadata, err error, canceled bool) {
+				switch {
+				case err == nil:
+					v.metadata, v.metadataErr = metadata, nil
+				case !canceled:
+					v.metadataErr, v.metadataFailedAt = err, v.timeFunc()
+				}
+			})
+		if ok {
+			return metadata, err
+		}
+	}
+}
+
+func (v *OIDCVerifier[T, PT]) fetchMetadata(ctx context.Context) (*ProviderMetadata, error) {
+	var metadata ProviderMetadata
+	err := v.getJSON(ctx, strings.TrimSuffix(v.issuer, "/")+DiscoveryPath, &metadata)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: %q", ErrIssuerMismatch, metadata.Issuer)
+	}
+	return &metadata, nil
+}
+
+// keySet 获取并缓存 JWKS.
+// force 为 true 时, 距离上次获取超过最小间隔则强制刷新.
+// 刷新失败时继续使用已缓存的 JWKS, 并且在 minRefreshInterval 内不再请求.
+func (v *OIDCVerifier[T, PT]) keySet(ctx context.Context, force bool) (JSONWebKeySet, error) {
+	metadata, err := v.providerMetadata(ctx)
+	if err != nil {
+		return JSONWebKeySet{}, err
+	}
+	fetch := func(ctx context.Context) (JSONWebKeySet, error) {
+		var keys JSONWebKeySet
+		err := v.getJSON(ctx, metadata.JWKSURI, &keys)
+		return keys, err
+	}
+	for {
+		v.mu.Lock()
+		now := v.timeFunc()
+		elapsed := now.Sub(v.fetchedAt)
+		fresh := !v.fetchedAt.IsZero() && elapsed < v.refreshInterval &&
+			(!force || elapsed < v.minRefreshInterval)
+		backoff := v.keysErr != nil && now.Sub(v.keysFailedAt) < v.minRefreshInterval
+		if fresh || backoff {
+			keys, err := v.cachedKeys()
+			v.mu.Unlock()
+			return keys, err
+		}
+		keys, ok, err := share(ctx, &v.mu, &v.keysFlight, fetch,
+			func(keys JSONWebKeySet, err error, canceled bool) {
+				switch {
+				case err == nil:
+					v.keys, v.keysErr = keys, nil
+					v.fetchedAt = v.timeFunc()
+				case !canceled:
+					v.keysErr, v.keysFailedAt = err, v.timeFunc()
+				}
+			})
+		if !ok {
+			continue
+		}
+		if err != nil && ctx.Err() == nil {
+			v.mu.Lock()
+			keys, err = v.cachedKeys()
+			v.mu.Unlock()
+		}
+		return keys, err
+	}
+}
+
+// cachedKeys 返回已缓存的 JWKS, 从未获取成功时返回上一次失败的原因.
+// 调用时必须持有 mu.
+func (v *OIDCVerifier[T, PT]) cachedKeys() (JSONWebKeySet, error) {
+	if v.fetchedAt.IsZero() {
+		return JSONWebKeySet{}, v.keysErr
+	}
+	return v.keys, nil
+}
+
+func (v *OIDCVerifier[T, PT]) getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwtcore: 请求 %s 失败: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
@@ -0,0 +1,452 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdP 是用于测试的 OpenID Provider.
+type testIdP struct {
+	*httptest.Server
+	mu           sync.Mutex
+	keys         JSONWebKeySet
+	jwksRequests atomic.Int32
+
+	discoveryRequests atomic.Int32
+	// discoveryHook 在响应配置文档前调用, 返回非 0 时以该状态码响应.
+	discoveryHook func() int
+	// jwksHook 在响应 JWKS 前调用, 返回非 0 时以该状态码响应.
+	jwksHook func() int
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	idp := &testIdP{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		idp.discoveryRequests.Add(1)
+		idp.mu.Lock()
+		hook := idp.discoveryHook
+		idp.mu.Unlock()
+		if hook != nil {
+			if status := hook(); status != 0 {
+				w.WriteHeader(status)
+				return
+			}
+		}
+		DiscoveryHandler(ProviderMetadata{
+			Issuer:                           idp.URL,
+			JWKSURI:                          idp.URL + "/jwks.json",
+			ResponseTypesSupported:           []string{"code"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: []string{"RS256", "ES256", "HS256"},
+		}).ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksRequests.Add(1)
+		idp.mu.Lock()
+		keys, hook := idp.keys, idp.jwksHook
+		idp.mu.Unlock()
+		if hook != nil {
+			if status := hook(); status != 0 {
+				w.WriteHeader(status)
+				return
+			}
+		}
+		JWKSHandler(keys).ServeHTTP(w, r)
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	idp.addKey(t, "rsa", &rsaPrivateKey.PublicKey, "RS256")
+	return idp
+}
+
+func (idp *testIdP) addKey(t *testing.T, kid string, key any, alg string) {
+	jwk, err := NewJSONWebKey(key, kid, alg)
+	require.NoError(t, err)
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys.Keys = append(idp.keys.Keys, jwk)
+}
+
+func (idp *testIdP) sign(t *testing.T, method jwt.SigningMethod, kid string,
+	key any, fn func(*IDTokenClaims)) string {
+	clm := IDTokenClaims{
+		Nonce: "nonce",
+		RegisteredClaims: RegisteredClaims{
+			Issuer:    idp.URL,
+			Subject:   "user",
+			Audience:  jwt.ClaimStrings{"client"},
+			IssuedAt:  jwt.NewNumericDate(nowTime),
+			ExpiresAt: jwt.NewNumericDate(nowTime.Add(defaultExpire)),
+		},
+	}
+	if fn != nil {
+		fn(&clm)
+	}
+	token := jwt.NewWithClaims(method, clm)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestOIDCVerifier_VerifyIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+	atHash, _ := TokenHash("RS256", "access-token")
+	tests := []struct {
+		name        string
+		token       func() string
+		nonce       string
+		accessToken string
+		wantErr     error
+	}{
+		{
+			name: "normal",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil)
+			},
+			nonce: "nonce",
+		},
+		{
+			name: "without_kid",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "", rsaPrivateKey, nil)
+			},
+		},
+		{
+			name: "at_hash",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.AccessTokenHash = atHash })
+			},
+			accessToken: "access-token",
+		},
+		{
+			name: "at_hash_mismatch",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.AccessTokenHash = atHash })
+			},
+			accessToken: "another-access-token",
+			wantErr:     ErrAccessTokenHashMismatch,
+		},
+		{
+			name: "nonce_mismatch",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil)
+			},
+			nonce:   "another-nonce",
+			wantErr: ErrNonceMismatch,
+		},
+		{
+			name: "bad_issuer",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" })
+			},
+			wantErr: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "bad_audience",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"another"} })
+			},
+			wantErr: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "multiple_audience_without_azp",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"client", "another"} })
+			},
+			wantErr: ErrAuthorizedPartyMismatch,
+		},
+		{
+			name: "multiple_audience_with_azp",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) {
+						c.Audience = jwt.ClaimStrings{"client", "another"}
+						c.AuthorizedParty = "client"
+					})
+			},
+		},
+		{
+			name: "expired",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(nowTime.Add(-time.Minute)) })
+			},
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name: "expired_within_leeway",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(nowTime.Add(-time.Second)) })
+			},
+		},
+		{
+			name: "missing_exp",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.ExpiresAt = nil })
+			},
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name: "missing_iat",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+					func(c *IDTokenClaims) { c.IssuedAt = nil })
+			},
+			wantErr: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			// 使用公钥作为 HMAC 密钥伪造的 token
+			name: "hmac_algorithm_confusion",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodHS256, "rsa", []byte(rsaPublicKeyPEM), nil)
+			},
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "unknown_kid",
+			token: func() string {
+				return idp.sign(t, jwt.SigningMethodRS256, "unknown", rsaPrivateKey, nil)
+			},
+			wantErr: ErrKeyNotFound,
+		},
+	}
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCLeeway(5*time.Second),
+		WithOIDCTimeFunc(func() time.Time { return nowTime }),
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.VerifyIDToken(context.Background(), tt.token(),
+				tt.nonce, tt.accessToken)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, IDTokenClaims{}, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user", got.Subject)
+		})
+	}
+}
+
+func TestOIDCVerifier_KeyRotation(t *testing.T) {
+	idp := newTestIdP(t)
+	now := nowTime
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCTimeFunc(func() time.Time { return now }),
+		WithOIDCRefreshInterval(time.Hour, time.Minute),
+	)
+	ctx := context.Background()
+	_, err := v.VerifyIDToken(ctx,
+		idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), idp.jwksRequests.Load())
+
+	// 缓存未过期时不会重复获取 JWKS.
+	_, err = v.VerifyIDToken(ctx,
+		idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), idp.jwksRequests.Load())
+
+	// 轮换新的密钥, 未知的 kid 触发刷新.
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	idp.addKey(t, "ec", &ecKey.PublicKey, "ES256")
+	now = now.Add(2 * time.Minute)
+	_, err = v.VerifyIDToken(ctx,
+		idp.sign(t, jwt.SigningMethodES256, "ec", ecKey, nil), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), idp.jwksRequests.Load())
+
+	// 未超过最小间隔时, 未知的 kid 不会触发刷新.
+	_, err = v.VerifyIDToken(ctx,
+		idp.sign(t, jwt.SigningMethodES256, "unknown", ecKey, nil), "", "")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, int32(2), idp.jwksRequests.Load())
+
+	// 缓存过期后重新获取.
+	now = now.Add(2 * time.Hour)
+	_, err = v.VerifyIDToken(ctx,
+		idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+			func(c *IDTokenClaims) {
+				c.IssuedAt = jwt.NewNumericDate(now)
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(defaultExpire))
+			}), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), idp.jwksRequests.Load())
+}
+
+func TestOIDCVerifier_IssuerMismatch(t *testing.T) {
+	idp := newTestIdP(t)
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL+"/", "client",
+		WithOIDCHTTPClient(idp.Client()),
+	)
+	_, err := v.VerifyIDToken(context.Background(),
+		idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil), "", "")
+	assert.ErrorIs(t, err, ErrIssuerMismatch)
+}
+
+func TestOIDCVerifier_ConcurrentFetch(t *testing.T) {
+	idp := newTestIdP(t)
+	release := make(chan struct{})
+	idp.discoveryHook = func() int {
+		<-release
+		return 0
+	}
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCTimeFunc(func() time.Time { return nowTime }),
+	)
+	token := idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil)
+
+	const n = 8
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := v.VerifyIDToken(context.Background(), token, "", "")
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return idp.discoveryRequests.Load() == 1 },
+		time.Second, time.Millisecond)
+
+	// 请求进行时不持有锁, ctx 结束的调用方立即返回.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := v.VerifyIDToken(ctx, token, "", "")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	for i := 0; i < n; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, int32(1), idp.discoveryRequests.Load())
+	assert.Equal(t, int32(1), idp.jwksRequests.Load())
+}
+
+func TestOIDCVerifier_DiscoveryFailure(t *testing.T) {
+	idp := newTestIdP(t)
+	var unavailable atomic.Bool
+	unavailable.Store(true)
+	idp.discoveryHook = func() int {
+		if unavailable.Load() {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	now := nowTime
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCTimeFunc(func() time.Time { return now }),
+		WithOIDCRefreshInterval(time.Hour, time.Minute),
+	)
+	token := idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil)
+	ctx := context.Background()
+
+	// 未超过最小间隔时不重复请求失败的配置文档.
+	for i := 0; i < 3; i++ {
+		_, err := v.VerifyIDToken(ctx, token, "", "")
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(1), idp.discoveryRequests.Load())
+
+	unavailable.Store(false)
+	now = now.Add(time.Minute)
+	_, err := v.VerifyIDToken(ctx, token, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), idp.discoveryRequests.Load())
+}
+
+func TestOIDCVerifier_JWKSFailure(t *testing.T) {
+	idp := newTestIdP(t)
+	var unavailable atomic.Bool
+	idp.jwksHook = func() int {
+		if unavailable.Load() {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	now := nowTime
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCTimeFunc(func() time.Time { return now }),
+		WithOIDCRefreshInterval(time.Hour, time.Minute),
+	)
+	ctx := context.Background()
+	sign := func() string {
+		return idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey,
+			func(c *IDTokenClaims) {
+				c.IssuedAt = jwt.NewNumericDate(now)
+				c.ExpiresAt = jwt.NewNumericDate(now.Add(defaultExpire))
+			})
+	}
+	_, err := v.VerifyIDToken(ctx, sign(), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), idp.jwksRequests.Load())
+
+	// 缓存过期后刷新失败, 继续使用已缓存的 JWKS, 并且在最小间隔内不再请求.
+	unavailable.Store(true)
+	now = now.Add(2 * time.Hour)
+	for i := 0; i < 3; i++ {
+		_, err = v.VerifyIDToken(ctx, sign(), "", "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), idp.jwksRequests.Load())
+
+	// 超过最小间隔后重试.
+	now = now.Add(time.Minute)
+	_, err = v.VerifyIDToken(ctx, sign(), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), idp.jwksRequests.Load())
+
+	unavailable.Store(false)
+	now = now.Add(time.Minute)
+	_, err = v.VerifyIDToken(ctx, sign(), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), idp.jwksRequests.Load())
+	_, err = v.VerifyIDToken(ctx, sign(), "", "")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), idp.jwksRequests.Load())
+}
+
+func TestOIDCVerifier_JWKSFailure_NoCache(t *testing.T) {
+	idp := newTestIdP(t)
+	idp.jwksHook = func() int { return http.StatusServiceUnavailable }
+	v := NewOIDCVerifier[IDTokenClaims](idp.URL, "client",
+		WithOIDCHTTPClient(idp.Client()),
+		WithOIDCTimeFunc(func() time.Time { return nowTime }),
+	)
+	token := idp.sign(t, jwt.SigningMethodRS256, "rsa", rsaPrivateKey, nil)
+
+	// 从未获取成功时返回错误, 并且在最小间隔内不再请求.
+	for i := 0; i < 3; i++ {
+		_, err := v.VerifyIDToken(context.Background(), token, "", "")
+		assert.ErrorContains(t, err, "503")
+	}
+	assert.Equal(t, int32(1), idp.jwksRequests.Load())
+}
@@ -1,9 +1,13 @@
 package jwtcore
 
 import (
+	"crypto/x509"
+	"log/slog"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/udugong/token"
 )
 
 // An Option configures a TokenManager.
@@ -32,8 +36,196 @@ func WithMethod[T jwt.Claims, PT Claims[T]](method jwt.SigningMethod) Option[T,
 	})
 }
 
-// WithTimeFunc 设置生成 jwt 的时间函数.
-// 可以固定生成 jwt 的时间.
+// WithAllowedAlgorithms 设置除 Method 外额外允许的签名算法.
+// 校验时默认只接受 Method 的签名算法, none 始终被拒绝.
+func WithAllowedAlgorithms[T jwt.Claims, PT Claims[T]](algs ...string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.allowedAlgorithms = algs
+	})
+}
+
+// WithAcceptedIssuers 设置除 Issuer 外额外接受的 iss.
+// 校验时 iss 必须是 Issuer 或其中之一, 两者都为空时不校验 iss.
+func WithAcceptedIssuers[T jwt.Claims, PT Claims[T]](issuers ...string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.acceptedIssuers = issuers
+	})
+}
+
+// WithAudiences 设置预期的 audience.
+// 校验时 aud 必须至少包含其中之一, 防止签发给其他服务的 token 被接受.
+func WithAudiences[T jwt.Claims, PT Claims[T]](audiences ...string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.audiences = audiences
+	})
+}
+
+// WithRequiredClaims 设置必需的 claims, 例如 "exp", "iat", "sub", "jti".
+// 校验时缺少任意一个 claim 都会失败, 错误包含 ErrClaimMissing.
+func WithRequiredClaims[T jwt.Claims, PT Claims[T]](claims ...string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.requiredClaims = claims
+	})
+}
+
+// WithValidators 添加 Validator, 在签名校验通过后按顺序执行.
+// 所有校验失败的错误会汇总到 *ValidationError.
+func WithValidators[T jwt.Claims, PT Claims[T]](validators ...Validator[T]) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.validators = append(t.validators[:len(t.validators):len(t.validators)], validators...)
+	})
+}
+
+// WithLeeway 设置校验 exp/nbf/iat 时允许的时钟偏差.
+func WithLeeway[T jwt.Claims, PT Claims[T]](leeway time.Duration) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.leeway = leeway
+	})
+}
+
+// WithMaxAge 设置从 iat 开始计算的最大有效期, 与 exp 无关.
+// 设置后 token 必须包含 iat.
+func WithMaxAge[T jwt.Claims, PT Claims[T]](maxAge time.Duration) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.maxAge = maxAge
+	})
+}
+
+// WithMaxTokenSize 设置 token 的最大长度 (字节), 超过时在解码前拒绝.
+// 错误包含 jwt.ErrTokenMalformed 与 ErrTokenTooLarge.
+func WithMaxTokenSize[T jwt.Claims, PT Claims[T]](size int) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.maxTokenSize = size
+	})
+}
+
+// WithMaxJSONDepth 设置头部与 payload 的最大 JSON 嵌套深度, 超过时在解析 JSON 前拒绝.
+// 错误包含 jwt.ErrTokenMalformed 与 ErrTokenTooDeep.
+func WithMaxJSONDepth[T jwt.Claims, PT Claims[T]](depth int) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.maxJSONDepth = depth
+	})
+}
+
+// WithVerifyConcurrency 设置 VerifyTokens 并行校验的最大并发数, 默认为 GOMAXPROCS.
+func WithVerifyConcurrency[T jwt.Claims, PT Claims[T]](n int) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.verifyConcurrency = n
+	})
+}
+
+// WithObserver 设置观察签发与校验的 Observer, 例如 ExpvarObserver.
+func WithObserver[T jwt.Claims, PT Claims[T]](o Observer) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.observer = o
+	})
+}
+
+// WithAuditLogger 使用 logger 记录签发与校验的审计日志.
+// 签发记录 Info, 校验失败记录 Warn, 校验通过记录 Debug; 日志中不包含 token 本身,
+// claims 中使用 AuditTag 标记的字段会被隐藏或忽略.
+func WithAuditLogger[T jwt.Claims, PT Claims[T]](logger *slog.Logger) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.auditLogger = logger
+	})
+}
+
+// WithTracer 设置创建签发与校验 span 的 Tracer, 例如 tokenotel.NewTracer.
+// 使用 GenerateTokenContext 与 VerifyTokenContext 时, span 是 ctx 中 span 的子 span.
+func WithTracer[T jwt.Claims, PT Claims[T]](tracer token.Tracer) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.tracer = tracer
+	})
+}
+
+// WithRejectFutureIssuedAt 拒绝 iat 晚于当前时间 (加上时钟偏差) 的 token.
+func WithRejectFutureIssuedAt[T jwt.Claims, PT Claims[T]]() Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.rejectFutureIAT = true
+	})
+}
+
+// WithType 设置签发 token 时 JOSE 头部的 typ, 例如 "at+jwt".
+func WithType[T jwt.Claims, PT Claims[T]](typ string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.setHeader("typ", typ)
+	})
+}
+
+// WithContentType 设置签发 token 时 JOSE 头部的 cty.
+func WithContentType[T jwt.Claims, PT Claims[T]](cty string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.setHeader("cty", cty)
+	})
+}
+
+// WithKeyID 设置签发 token 时 JOSE 头部的 kid.
+// Signer 的 KeyID 不为空时优先使用 Signer 的 KeyID.
+func WithKeyID[T jwt.Claims, PT Claims[T]](kid string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.setHeader("kid", kid)
+	})
+}
+
+// WithX509CertChain 设置签发 token 时 JOSE 头部的 x5c 与 x5t.
+// chain 的第一个证书必须是签名密钥对应的证书.
+func WithX509CertChain[T jwt.Claims, PT Claims[T]](chain ...*x509.Certificate) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.setHeader("x5c", encodeCertChain(chain))
+		if len(chain) > 0 {
+			t.setHeader("x5t", certificateSHA1Thumbprint(chain[0]))
+		}
+	})
+}
+
+// WithJWKSetURL 设置签发 token 时 JOSE 头部的 jku.
+func WithJWKSetURL[T jwt.Claims, PT Claims[T]](jku string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.setHeader("jku", jku)
+	})
+}
+
+// WithGenHeaderFunc 设置签发 token 时生成 JOSE 头部的函数.
+// 返回的头部会覆盖其他头部选项, alg 除外.
+func WithGenHeaderFunc[T jwt.Claims, PT Claims[T]](fn func() map[string]any) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.genHeaderFn = fn
+	})
+}
+
+// WithExpectedType 设置校验时要求的 typ, 比较时忽略大小写与 "application/" 前缀.
+// 可以防止 ID token、refresh token 等被当作 access token 使用.
+func WithExpectedType[T jwt.Claims, PT Claims[T]](typ string) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.expectedType = typ
+	})
+}
+
+// WithX509Roots 设置校验 x5c 证书链的根证书与校验叶子证书的 verifyLeaf.
+// 设置后, 带有 x5c 的 token 使用校验通过的叶子证书的公钥校验签名.
+// 根证书签发的证书不一定都可以签发 token, verifyLeaf 用于限制叶子证书的 DNS 名称、主题等,
+// 为 nil 时拒绝所有带有 x5c 的 token.
+func WithX509Roots[T jwt.Claims, PT Claims[T]](roots *x509.CertPool,
+	verifyLeaf X509LeafVerifier) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.x509Roots = roots
+		t.x509VerifyLeaf = verifyLeaf
+	})
+}
+
+// WithSigner 设置签名器, 生成 jwt 时不再使用 EncryptionKey 签名.
+// 签名方式会被设置为 signer.Algorithm() 对应的 jwt.SigningMethod.
+func WithSigner[T jwt.Claims, PT Claims[T]](signer Signer) Option[T, PT] {
+	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
+		t.signer = signer
+		if method := jwt.GetSigningMethod(signer.Algorithm()); method != nil {
+			t.Method = method
+		}
+	})
+}
+
+// WithTimeFunc 设置生成与校验 jwt 的时间函数.
+// 可以固定生成与校验 jwt 的时间.
 func WithTimeFunc[T jwt.Claims, PT Claims[T]](fn func() time.Time) Option[T, PT] {
 	return optionFunc[T, PT](func(t *TokenManager[T, PT]) {
 		t.timeFunc = fn
@@ -1,11 +1,15 @@
 package jwtcore
 
 import (
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/udugong/token/tokentest"
 )
 
 func TestWithDecryptKey(t *testing.T) {
@@ -345,6 +349,224 @@ func TestWithGenIDFunc(t *testing.T) {
 	}
 }
 
+func TestWithAllowedAlgorithms(t *testing.T) {
+	type testCase[T jwt.Claims, PT Claims[T]] struct {
+		name string
+		fn   func() Option[T, PT]
+		want []string
+	}
+	tests := []testCase[MyClaims, *MyClaims]{
+		{
+			name: "normal",
+			fn:   withNop[MyClaims],
+			want: nil,
+		},
+		{
+			name: "set_allowed_algorithms",
+			fn: func() Option[MyClaims, *MyClaims] {
+				return WithAllowedAlgorithms[MyClaims]("HS384", "HS512")
+			},
+			want: []string{"HS384", "HS512"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTokenManager[MyClaims](
+				encryptionKey, defaultExpire, tt.fn()).allowedAlgorithms
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithAcceptedIssuers(t *testing.T) {
+	type testCase[T jwt.Claims, PT Claims[T]] struct {
+		name string
+		fn   func() Option[T, PT]
+		want []string
+	}
+	tests := []testCase[MyClaims, *MyClaims]{
+		{
+			name: "normal",
+			fn:   withNop[MyClaims],
+			want: nil,
+		},
+		{
+			name: "set_accepted_issuers",
+			fn: func() Option[MyClaims, *MyClaims] {
+				return WithAcceptedIssuers[MyClaims]("service-a", "service-b")
+			},
+			want: []string{"service-a", "service-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTokenManager[MyClaims](
+				encryptionKey, defaultExpire, tt.fn()).acceptedIssuers
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithAudiences(t *testing.T) {
+	type testCase[T jwt.Claims, PT Claims[T]] struct {
+		name string
+		fn   func() Option[T, PT]
+		want []string
+	}
+	tests := []testCase[MyClaims, *MyClaims]{
+		{
+			name: "normal",
+			fn:   withNop[MyClaims],
+			want: nil,
+		},
+		{
+			name: "set_audiences",
+			fn: func() Option[MyClaims, *MyClaims] {
+				return WithAudiences[MyClaims]("api-a", "api-b")
+			},
+			want: []string{"api-a", "api-b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTokenManager[MyClaims](
+				encryptionKey, defaultExpire, tt.fn()).audiences
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithRequiredClaims(t *testing.T) {
+	type testCase[T jwt.Claims, PT Claims[T]] struct {
+		name string
+		fn   func() Option[T, PT]
+		want []string
+	}
+	tests := []testCase[MyClaims, *MyClaims]{
+		{
+			name: "normal",
+			fn:   withNop[MyClaims],
+			want: nil,
+		},
+		{
+			name: "set_required_claims",
+			fn: func() Option[MyClaims, *MyClaims] {
+				return WithRequiredClaims[MyClaims]("exp", "jti")
+			},
+			want: []string{"exp", "jti"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTokenManager[MyClaims](
+				encryptionKey, defaultExpire, tt.fn()).requiredClaims
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithValidators(t *testing.T) {
+	nop := ValidatorFunc[MyClaims](func(MyClaims) error { return nil })
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithValidators[MyClaims](nop))
+	assert.Len(t, m.validators, 1)
+	// 添加的 Validator 不会影响原来的 TokenManager
+	m2 := m.WithOptions(WithValidators[MyClaims](nop, nop))
+	assert.Len(t, m.validators, 1)
+	assert.Len(t, m2.validators, 3)
+}
+
+func TestWithLeeway(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Equal(t, time.Duration(0), m.leeway)
+	assert.Equal(t, 5*time.Second,
+		m.WithOptions(WithLeeway[MyClaims](5*time.Second)).leeway)
+}
+
+func TestWithMaxAge(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Equal(t, time.Duration(0), m.maxAge)
+	assert.Equal(t, time.Hour, m.WithOptions(WithMaxAge[MyClaims](time.Hour)).maxAge)
+}
+
+func TestWithMaxTokenSize(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Equal(t, 0, m.maxTokenSize)
+	assert.Equal(t, 4096, m.WithOptions(WithMaxTokenSize[MyClaims](4096)).maxTokenSize)
+}
+
+func TestWithMaxJSONDepth(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Equal(t, 0, m.maxJSONDepth)
+	assert.Equal(t, 8, m.WithOptions(WithMaxJSONDepth[MyClaims](8)).maxJSONDepth)
+}
+
+func TestWithVerifyConcurrency(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Equal(t, 0, m.verifyConcurrency)
+	assert.Equal(t, 4, m.WithOptions(WithVerifyConcurrency[MyClaims](4)).verifyConcurrency)
+}
+
+func TestWithObserver(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Nil(t, m.observer)
+	o := ObserverFuncs{}
+	assert.Equal(t, o, m.WithOptions(WithObserver[MyClaims](o)).observer)
+}
+
+func TestWithRejectFutureIssuedAt(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.False(t, m.rejectFutureIAT)
+	assert.True(t, m.WithOptions(WithRejectFutureIssuedAt[MyClaims]()).rejectFutureIAT)
+}
+
+func TestWithSigner(t *testing.T) {
+	signer := NewKeySigner(jwt.SigningMethodHS512, []byte(encryptionKey), "")
+	type testCase[T jwt.Claims, PT Claims[T]] struct {
+		name       string
+		fn         func() Option[T, PT]
+		wantSigner Signer
+		wantMethod jwt.SigningMethod
+	}
+	tests := []testCase[MyClaims, *MyClaims]{
+		{
+			name:       "normal",
+			fn:         withNop[MyClaims],
+			wantSigner: nil,
+			wantMethod: jwt.SigningMethodHS256,
+		},
+		{
+			name: "set_signer",
+			fn: func() Option[MyClaims, *MyClaims] {
+				return WithSigner[MyClaims](signer)
+			},
+			wantSigner: signer,
+			wantMethod: jwt.SigningMethodHS512,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTokenManager[MyClaims](encryptionKey, defaultExpire, tt.fn())
+			assert.Equal(t, tt.wantSigner, got.signer)
+			assert.Equal(t, tt.wantMethod, got.Method)
+		})
+	}
+}
+
 func withNop[T jwt.Claims, PT Claims[T]]() Option[T, PT] {
 	return optionFunc[T, PT](func(m *TokenManager[T, PT]) {})
 }
+
+func TestWithAuditLogger(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Nil(t, m.auditLogger)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	assert.Same(t, logger, m.WithOptions(WithAuditLogger[MyClaims](logger)).auditLogger)
+}
+
+func TestWithTracer(t *testing.T) {
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire)
+	assert.Nil(t, m.tracer)
+	r := tokentest.NewSpanRecorder()
+	assert.Same(t, r, m.WithOptions(WithTracer[MyClaims](r)).tracer)
+}
@@ -0,0 +1,195 @@
+package jwtcore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Registry 根据未校验的 token 选择 jwt 管理器, 用于多租户、多签发人的校验.
+// 默认使用 iss 选择 jwt 管理器, 也可以使用租户 claim 或 kid 前缀.
+// 选中的 jwt 管理器会完整地校验 token, 路由使用的 claim 在校验通过前不可信,
+// 因此每个租户的 jwt 管理器都应该使用独立的密钥.
+// Registry 可以安全地在多个 goroutine 中使用.
+type Registry[T jwt.Claims, PT Claims[T]] struct {
+	mu       sync.RWMutex
+	managers map[string]*TokenManager[T, PT]
+
+	registryConfig
+}
+
+// A RegistryOption configures a Registry.
+type RegistryOption interface {
+	apply(*registryConfig)
+}
+
+type registryConfig struct {
+	tenantClaim  string // 为空时使用 iss 选择
+	kidPrefix    bool   // 使用 kid 前缀选择
+	maxTokenSize int    // 路由前检查的 token 最大长度, 为 0 时不限制
+	maxJSONDepth int    // 路由前检查的 JSON 最大嵌套深度, 为 0 时不限制
+	auditLogger  *slog.Logger
+}
+
+// registryOptionFunc wraps a func, so it satisfies the RegistryOption interface.
+type registryOptionFunc func(*registryConfig)
+
+func (f registryOptionFunc) apply(c *registryConfig) {
+	f(c)
+}
+
+// WithRegistryTenantClaim 使用指定 claim 的值选择 jwt 管理器, 例如 "tenant_id".
+func WithRegistryTenantClaim(claim string) RegistryOption {
+	return registryOptionFunc(func(c *registryConfig) {
+		c.tenantClaim = claim
+		c.kidPrefix = false
+	})
+}
+
+// WithRegistryKeyIDPrefix 使用 kid 选择 jwt 管理器.
+// 注册的 key 是 kid 的前缀, 有多个匹配时选择最长的前缀.
+func WithRegistryKeyIDPrefix() RegistryOption {
+	return registryOptionFunc(func(c *registryConfig) {
+		c.kidPrefix = true
+		c.tenantClaim = ""
+	})
+}
+
+// WithRegistryMaxTokenSize 设置 token 的最大长度 (字节), 超过时在解码前拒绝.
+// 路由需要解码未校验的 token, 因此 jwt 管理器的 WithMaxTokenSize 不足以保护 Registry.
+// 错误包含 jwt.ErrTokenMalformed 与 ErrTokenTooLarge.
+func WithRegistryMaxTokenSize(size int) RegistryOption {
+	return registryOptionFunc(func(c *registryConfig) {
+		c.maxTokenSize = size
+	})
+}
+
+// WithRegistryMaxJSONDepth 设置头部与 payload 的最大 JSON 嵌套深度, 超过时在路由前拒绝.
+// 错误包含 jwt.ErrTokenMalformed 与 ErrTokenTooDeep.
+func WithRegistryMaxJSONDepth(depth int) RegistryOption {
+	return registryOptionFunc(func(c *registryConfig) {
+		c.maxJSONDepth = depth
+	})
+}
+
+// WithRegistryAuditLogger 使用 logger 记录无法选择 jwt 管理器的 token.
+// 需要记录每个租户的签发与校验时, 在各自的 jwt 管理器中使用 WithAuditLogger.
+func WithRegistryAuditLogger(logger *slog.Logger) RegistryOption {
+	return registryOptionFunc(func(c *registryConfig) {
+		c.auditLogger = logger
+	})
+}
+
+// NewRegistry 创建 Registry.
+func NewRegistry[T jwt.Claims, PT Claims[T]](opts ...RegistryOption) *Registry[T, PT] {
+	r := &Registry[T, PT]{managers: make(map[string]*TokenManager[T, PT])}
+	for _, opt := range opts {
+		opt.apply(&r.registryConfig)
+	}
+	return r
+}
+
+// Register 注册 key 对应的 jwt 管理器, 已存在时替换.
+// key 根据 Registry 的路由方式为 iss、租户 claim 的值或 kid 前缀.
+func (r *Registry[T, PT]) Register(key string, manager *TokenManager[T, PT]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.managers[key] = manager
+}
+
+// Unregister 移除 key 对应的 jwt 管理器.
+func (r *Registry[T, PT]) Unregister(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.managers, key)
+}
+
+// Lookup 返回 key 对应的 jwt 管理器.
+func (r *Registry[T, PT]) Lookup(key string) (*TokenManager[T, PT], bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.managers[key]
+	return m, ok
+}
+
+// Keys 返回所有已注册的 key.
+func (r *Registry[T, PT]) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.managers))
+	for k := range r.managers {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// VerifyToken 根据未校验的 token 选择 jwt 管理器, 并使用它校验 token.
+// 没有对应的 jwt 管理器时返回的错误包含 ErrTenantNotFound.
+func (r *Registry[T, PT]) VerifyToken(token string) (T, error) {
+	return r.VerifyTokenContext(context.Background(), token)
+}
+
+// VerifyTokenContext 与 VerifyToken 相同, ctx 传递给选中的 jwt 管理器.
+func (r *Registry[T, PT]) VerifyTokenContext(ctx context.Context, token string) (T, error) {
+	var zeroClm T
+	m, err := r.route(token)
+	if err != nil {
+		if r.auditLogger != nil {
+			auditRouted(ctx, r.auditLogger, token, err)
+		}
+		return zeroClm, err
+	}
+	clm, err := m.VerifyTokenContext(ctx, token)
+	if err != nil {
+		return zeroClm, err
+	}
+	return clm, nil
+}
+
+// route 解析未校验的 token 并选择 jwt 管理器.
+func (r *Registry[T, PT]) route(token string) (*TokenManager[T, PT], error) {
+	if err := checkTokenLimits(token, r.maxTokenSize, r.maxJSONDepth); err != nil {
+		return nil, fmt.Errorf("验证失败: %w", err)
+	}
+	claims := jwt.MapClaims{}
+	unverified, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return nil, fmt.Errorf("验证失败: %w", err)
+	}
+	if r.kidPrefix {
+		kid, _ := unverified.Header["kid"].(string)
+		if m, ok := r.lookupPrefix(kid); ok {
+			return m, nil
+		}
+		return nil, fmt.Errorf("%w: kid=%q", ErrTenantNotFound, kid)
+	}
+	name := "iss"
+	if r.tenantClaim != "" {
+		name = r.tenantClaim
+	}
+	key, _ := claims[name].(string)
+	if m, ok := r.Lookup(key); ok && key != "" {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w: %s=%q", ErrTenantNotFound, name, key)
+}
+
+// lookupPrefix 返回 kid 最长前缀对应的 jwt 管理器.
+func (r *Registry[T, PT]) lookupPrefix(kid string) (*TokenManager[T, PT], bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var (
+		matched *TokenManager[T, PT]
+		longest = -1
+	)
+	for prefix, m := range r.managers {
+		if strings.HasPrefix(kid, prefix) && len(prefix) > longest {
+			matched, longest = m, len(prefix)
+		}
+	}
+	return matched, matched != nil
+}
@@ -0,0 +1,205 @@
+package jwtcore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type TenantClaims struct {
+	Uid      int64  `json:"uid"`
+	TenantID string `json:"tenant_id"`
+	RegisteredClaims
+}
+
+func TestRegistry_VerifyToken(t *testing.T) {
+	tenantA := NewTokenManager[TenantClaims]("key a", defaultExpire,
+		WithIssuer[TenantClaims]("https://a.example.com"), WithKeyID[TenantClaims]("a/1"))
+	tenantB := NewTokenManager[TenantClaims]("key b", defaultExpire,
+		WithIssuer[TenantClaims]("https://b.example.com"), WithKeyID[TenantClaims]("b/1"))
+	unknown := NewTokenManager[TenantClaims]("key c", defaultExpire,
+		WithIssuer[TenantClaims]("https://c.example.com"), WithKeyID[TenantClaims]("c/1"))
+	issue := func(m *TokenManager[TenantClaims, *TenantClaims], tenant string) string {
+		token, err := m.GenerateToken(TenantClaims{Uid: 1, TenantID: tenant})
+		require.NoError(t, err)
+		return token
+	}
+	// 使用租户 B 的 iss, 但使用租户 A 的密钥签名
+	forged := issue(tenantA.WithOptions(
+		WithIssuer[TenantClaims]("https://b.example.com")), "b")
+
+	tests := []struct {
+		name     string
+		registry func() *Registry[TenantClaims, *TenantClaims]
+		token    string
+		wantIss  string
+		wantErr  error
+	}{
+		{
+			name: "issuer",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims]()
+				r.Register("https://a.example.com", tenantA)
+				r.Register("https://b.example.com", tenantB)
+				return r
+			},
+			token:   issue(tenantB, "b"),
+			wantIss: "https://b.example.com",
+		},
+		{
+			name: "issuer_not_found",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims]()
+				r.Register("https://a.example.com", tenantA)
+				return r
+			},
+			token:   issue(unknown, "c"),
+			wantErr: ErrTenantNotFound,
+		},
+		{
+			name: "forged_issuer",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims]()
+				r.Register("https://a.example.com", tenantA)
+				r.Register("https://b.example.com", tenantB)
+				return r
+			},
+			token:   forged,
+			wantErr: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "tenant_claim",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims](WithRegistryTenantClaim("tenant_id"))
+				r.Register("a", tenantA)
+				r.Register("b", tenantB)
+				return r
+			},
+			token:   issue(tenantA, "a"),
+			wantIss: "https://a.example.com",
+		},
+		{
+			name: "tenant_claim_not_found",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims](WithRegistryTenantClaim("tenant_id"))
+				r.Register("a", tenantA)
+				return r
+			},
+			token:   issue(tenantA, ""),
+			wantErr: ErrTenantNotFound,
+		},
+		{
+			name: "kid_prefix",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims](WithRegistryKeyIDPrefix())
+				r.Register("a/", tenantA)
+				r.Register("b", unknown)
+				r.Register("b/", tenantB)
+				return r
+			},
+			token:   issue(tenantB, "b"),
+			wantIss: "https://b.example.com",
+		},
+		{
+			name: "kid_prefix_not_found",
+			registry: func() *Registry[TenantClaims, *TenantClaims] {
+				r := NewRegistry[TenantClaims](WithRegistryKeyIDPrefix())
+				r.Register("a/", tenantA)
+				return r
+			},
+			token:   issue(unknown, "c"),
+			wantErr: ErrTenantNotFound,
+		},
+		{
+			name:     "bad_token",
+			registry: func() *Registry[TenantClaims, *TenantClaims] { return NewRegistry[TenantClaims]() },
+			token:    "bad_token",
+			wantErr:  jwt.ErrTokenMalformed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.registry().VerifyToken(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIss, got.Issuer)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry[TenantClaims]()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		iss := fmt.Sprintf("https://%d.example.com", i)
+		m := NewTokenManager[TenantClaims](iss, defaultExpire, WithIssuer[TenantClaims](iss))
+		token, err := m.GenerateToken(TenantClaims{Uid: 1})
+		require.NoError(t, err)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			r.Register(iss, m)
+		}()
+		go func() {
+			defer wg.Done()
+			// 注册前找不到租户
+			if _, err := r.VerifyToken(token); err != nil {
+				assert.ErrorIs(t, err, ErrTenantNotFound)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, r.Keys(), 10)
+
+	r.Unregister("https://0.example.com")
+	_, ok := r.Lookup("https://0.example.com")
+	assert.False(t, ok)
+	keys := r.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, "https://1.example.com", keys[0])
+}
+
+func TestRegistry_Limits(t *testing.T) {
+	r := NewRegistry[MyClaims](WithRegistryMaxTokenSize(512), WithRegistryMaxJSONDepth(8))
+	deep := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:    "too_large",
+			token:   signRaw(t, hs256Header, `{"iss":"`+strings.Repeat("a", 512)+`"}`),
+			wantErr: ErrTokenTooLarge,
+		},
+		{
+			name:    "too_deep",
+			token:   signRaw(t, hs256Header, `{"iss":"","x":`+deep+`}`),
+			wantErr: ErrTokenTooDeep,
+		},
+		{
+			name:    "within_limits",
+			token:   signRaw(t, hs256Header, `{"iss":"https://a.example.com"}`),
+			wantErr: ErrTenantNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.VerifyToken(tt.token)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != ErrTenantNotFound {
+				assert.ErrorIs(t, err, jwt.ErrTokenMalformed)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package jwtcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ReloadingManager 从配置文件创建 jwt 管理器, 并在配置文件或密钥文件变化时重新加载.
+// 重新加载后, 上一个 jwt 管理器在宽限期内仍然可以校验 token, 用于密钥轮换.
+// 重新加载失败时保留当前的 jwt 管理器, 并通过 WithReloadErrorHandler 报告错误.
+// ReloadingManager 可以安全地在多个 goroutine 中使用.
+type ReloadingManager[T jwt.Claims, PT Claims[T]] struct {
+	path        string
+	managerOpts []Option[T, PT]
+	state       atomic.Pointer[reloadState[T, PT]]
+
+	mu           sync.Mutex // 串行化重新加载
+	failedDigest []byte     // 上一次加载失败时文件的摘要, 避免重复报告同一个错误
+	failedErr    string     // 上一次加载失败的原因, 文件不可读时用于去重
+
+	reloadConfig
+}
+
+// reloadState 是一次加载的结果, 创建后不再修改.
+type reloadState[T jwt.Claims, PT Claims[T]] struct {
+	manager       *TokenManager[T, PT]
+	previous      *TokenManager[T, PT] // 上一个 jwt 管理器
+	previousUntil time.Time            // previous 的宽限期
+	files         []string             // 监视的文件
+	digest        []byte               // 加载时文件的摘要
+}
+
+type reloadConfig struct {
+	interval    time.Duration
+	grace       time.Duration // 为 0 时使用上一个 jwt 管理器的有效期
+	envPrefix   string
+	onError     func(error)
+	timeFunc    func() time.Time
+	auditLogger *slog.Logger
+}
+
+// A ReloadOption configures a ReloadingManager.
+type ReloadOption interface {
+	apply(*reloadConfig)
+}
+
+// reloadOptionFunc wraps a func, so it satisfies the ReloadOption interface.
+type reloadOptionFunc func(*reloadConfig)
+
+func (f reloadOptionFunc) apply(c *reloadConfig) {
+	f(c)
+}
+
+// defaultReloadInterval 是轮询文件的默认间隔.
+const defaultReloadInterval = 5 * time.Second
+
+// WithReloadInterval 设置轮询文件的间隔, 默认为 5 秒. 小于等于 0 时使用默认值.
+func WithReloadInterval(interval time.Duration) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.interval = interval
+	})
+}
+
+// WithReloadGracePeriod 设置上一个 jwt 管理器的宽限期.
+// 默认为上一个 jwt 管理器的有效期加上时钟偏差, 即旧密钥签发的 token 过期前仍然可以通过校验.
+func WithReloadGracePeriod(grace time.Duration) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.grace = grace
+	})
+}
+
+// WithReloadEnvPrefix 设置加载配置文件后使用的环境变量前缀, 参考 Config.ApplyEnv.
+func WithReloadEnvPrefix(prefix string) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.envPrefix = prefix
+	})
+}
+
+// WithReloadErrorHandler 设置重新加载失败时的回调.
+func WithReloadErrorHandler(fn func(error)) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.onError = fn
+	})
+}
+
+// WithReloadTimeFunc 设置计算宽限期的时间函数.
+func WithReloadTimeFunc(fn func() time.Time) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.timeFunc = fn
+	})
+}
+
+// WithReloadAuditLogger 使用 logger 记录重新加载的结果.
+// 需要记录签发与校验时, 在 managerOpts 中使用 WithAuditLogger.
+func WithReloadAuditLogger(logger *slog.Logger) ReloadOption {
+	return reloadOptionFunc(func(c *reloadConfig) {
+		c.auditLogger = logger
+	})
+}
+
+// NewReloadingManager 从配置文件创建 ReloadingManager, 配置文件的格式参考 LoadConfig.
+// managerOpts 在配置之后应用, 每次重新加载都会使用.
+// 需要调用 Watch 监视文件的变化.
+func NewReloadingManager[T jwt.Claims, PT Claims[T]](path string, managerOpts []Option[T, PT],
+	opts ...ReloadOption) (*ReloadingManager[T, PT], error) {
+	m := &ReloadingManager[T, PT]{
+		path:        path,
+		managerOpts: managerOpts,
+		reloadConfig: reloadConfig{
+			interval: defaultReloadInterval,
+			onError:  func(error) {},
+			timeFunc: time.Now,
+		},
+	}
+	for _, opt := range opts {
+		opt.apply(&m.reloadConfig)
+	}
+	if m.interval <= 0 {
+		m.interval = defaultReloadInterval
+	}
+	state, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	m.state.Store(state)
+	return m, nil
+}
+
+// Manager 返回当前的 jwt 管理器.
+func (m *ReloadingManager[T, PT]) Manager() *TokenManager[T, PT] {
+	return m.state.Load().manager
+}
+
+// GenerateToken 使用当前的 jwt 管理器生成 token.
+func (m *ReloadingManager[T, PT]) GenerateToken(clm T) (string, error) {
+	return m.Manager().GenerateToken(clm)
+}
+
+// GenerateTokenContext 使用当前的 jwt 管理器生成 token.
+func (m *ReloadingManager[T, PT]) GenerateTokenContext(ctx context.Context, clm T) (string, error) {
+	return m.Manager().GenerateTokenContext(ctx, clm)
+}
+
+// VerifyToken 使用当前的 jwt 管理器校验 token.
+// 校验失败时, 在宽限期内使用上一个 jwt 管理器再次校验.
+func (m *ReloadingManager[T, PT]) VerifyToken(token string) (T, error) {
+	return m.VerifyTokenContext(context.Background(), token)
+}
+
+// VerifyTokenContext 与 VerifyToken 相同, 使用上一个 jwt 管理器再次校验时会产生第二个 span.
+func (m *ReloadingManager[T, PT]) VerifyTokenContext(ctx context.Context, token string) (T, error) {
+	state := m.state.Load()
+	clm, err := state.manager.VerifyTokenContext(ctx, token)
+	if err != nil && state.previous != nil && m.timeFunc().Before(state.previousUntil) {
+		if prevClm, prevErr := state.previous.VerifyTokenContext(ctx, token); prevErr == nil {
+			return prevClm, nil
+		}
+	}
+	return clm, err
+}
+
+// Reload 重新加载配置文件与密钥文件.
+// 加载失败时保留当前的 jwt 管理器, 并返回错误.
+func (m *ReloadingManager[T, PT]) Reload() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.reload()
+	m.audit(err)
+	return err
+}
+
+// Watch 按照 WithReloadInterval 设置的间隔轮询配置文件与密钥文件,
+// 文件变化时重新加载, 直到 ctx 结束.
+func (m *ReloadingManager[T, PT]) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.poll()
+		}
+	}
+}
+
+// poll 检查文件是否变化, 变化时重新加载.
+func (m *ReloadingManager[T, PT]) poll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	digest, err := digestFiles(m.state.Load().files)
+	if err == nil && (bytes.Equal(digest, m.state.Load().digest) ||
+		bytes.Equal(digest, m.failedDigest)) {
+		return
+	}
+	err = m.reload()
+	if err != nil {
+		// 文件不可读 (例如正在替换) 时没有摘要, 相同的错误只报告一次.
+		if digest == nil && err.Error() == m.failedErr {
+			return
+		}
+		m.failedDigest, m.failedErr = digest, err.Error()
+		m.audit(err)
+		m.onError(err)
+		return
+	}
+	m.audit(nil)
+}
+
+// audit 记录重新加载的结果.
+func (m *ReloadingManager[T, PT]) audit(err error) {
+	if m.auditLogger == nil {
+		return
+	}
+	ctx := context.Background()
+	if err != nil {
+		m.auditLogger.LogAttrs(ctx, slog.LevelError, "重新加载 jwt 管理器失败",
+			slog.String("event", auditEventReload),
+			slog.String("path", m.path),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	state := m.state.Load()
+	m.auditLogger.LogAttrs(ctx, slog.LevelInfo, "重新加载 jwt 管理器",
+		slog.String("event", auditEventReload),
+		slog.String("path", m.path),
+		slog.String("alg", state.manager.Method.Alg()),
+		slog.Time("previous_until", state.previousUntil),
+	)
+}
+
+// reload 加载新的 jwt 管理器并替换当前的 jwt 管理器, 调用方需要持有 mu.
+func (m *ReloadingManager[T, PT]) reload() error {
+	state, err := m.load()
+	if err != nil {
+		return err
+	}
+	current := m.state.Load()
+	grace := m.grace
+	if grace <= 0 {
+		grace = current.manager.Expire + current.manager.leeway
+	}
+	state.previous = current.manager
+	state.previousUntil = m.timeFunc().Add(grace)
+	m.state.Store(state)
+	m.failedDigest, m.failedErr = nil, ""
+	return nil
+}
+
+// load 读取配置文件与密钥文件并创建 jwt 管理器.
+func (m *ReloadingManager[T, PT]) load() (*reloadState[T, PT], error) {
+	cfg, err := LoadConfig(m.path)
+	if err != nil {
+		return nil, err
+	}
+	if m.envPrefix != "" {
+		if err = cfg.ApplyEnv(m.envPrefix); err != nil {
+			return nil, err
+		}
+	}
+	files := []string{m.path}
+	for _, f := range []string{cfg.SigningKey.File, cfg.VerifyKey.File} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	// 在读取密钥前计算摘要, 读取期间文件发生变化时, 下一次轮询会再次加载.
+	digest, err := digestFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := NewFromConfig[T, PT](cfg, m.managerOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadState[T, PT]{manager: manager, files: files, digest: digest}, nil
+}
+
+// digestFiles 计算所有文件内容的摘要.
+func digestFiles(files []string) ([]byte, error) {
+	h := sha256.New()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		h.Write(sum[:])
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,196 @@
+package jwtcore
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token/tokentest"
+)
+
+// writeReloadConfig 写入 JSON 格式的配置文件.
+func writeReloadConfig(t *testing.T, path string, cfg Config) {
+	b, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	// 先写入临时文件再重命名, 避免读取到写了一半的文件.
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, b, 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestReloadingManager_Reload(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	configFile := filepath.Join(dir, "jwt.json")
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 1"), 0o600))
+	writeReloadConfig(t, configFile, Config{
+		SigningKey: KeySource{File: keyFile},
+		Expire:     Duration(time.Hour),
+	})
+
+	now := time.Now()
+	var errs []error
+	m, err := NewReloadingManager[MyClaims](configFile, nil,
+		WithReloadGracePeriod(time.Minute),
+		WithReloadTimeFunc(func() time.Time { return now }),
+		WithReloadErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	require.NoError(t, err)
+	oldToken, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	// 文件没有变化时不重新加载
+	manager := m.Manager()
+	m.poll()
+	assert.Same(t, manager, m.Manager())
+
+	// 轮换密钥
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 2"), 0o600))
+	m.poll()
+	assert.NotSame(t, manager, m.Manager())
+	assert.Equal(t, "key 2", m.Manager().EncryptionKey)
+	newToken, err := m.GenerateToken(MyClaims{Uid: 2})
+	require.NoError(t, err)
+	got, err := m.VerifyToken(newToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Uid)
+	// 宽限期内旧密钥签发的 token 仍然有效
+	got, err = m.VerifyToken(oldToken)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.Uid)
+
+	// 错误的配置不会替换当前的 jwt 管理器, 并且只报告一次
+	manager = m.Manager()
+	writeReloadConfig(t, configFile, Config{
+		SigningKey: KeySource{File: keyFile},
+		Algorithm:  "none",
+	})
+	m.poll()
+	m.poll()
+	assert.Same(t, manager, m.Manager())
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrUnsupportedAlgorithm)
+	assert.Error(t, m.Reload())
+
+	// 修复配置后重新加载
+	writeReloadConfig(t, configFile, Config{
+		SigningKey: KeySource{File: keyFile},
+		Expire:     Duration(time.Hour),
+		Issuer:     "issuer",
+	})
+	m.poll()
+	assert.Equal(t, "issuer", m.Manager().Issuer)
+	assert.Len(t, errs, 1)
+
+	// 密钥文件不可读时不替换当前的 jwt 管理器, 并且只报告一次
+	manager = m.Manager()
+	require.NoError(t, os.Rename(keyFile, keyFile+".bak"))
+	m.poll()
+	m.poll()
+	assert.Same(t, manager, m.Manager())
+	require.Len(t, errs, 2)
+	assert.ErrorIs(t, errs[1], os.ErrNotExist)
+	require.NoError(t, os.Rename(keyFile+".bak", keyFile))
+	m.poll()
+	assert.Len(t, errs, 2)
+
+	// 宽限期结束后旧密钥签发的 token 无效
+	now = now.Add(2 * time.Minute)
+	_, err = m.VerifyToken(oldToken)
+	assert.Error(t, err)
+}
+
+func TestNewReloadingManager_Interval(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "jwt.json")
+	writeReloadConfig(t, configFile, Config{SigningKey: KeySource{Inline: "key"}, Expire: Duration(time.Hour)})
+	for _, interval := range []time.Duration{0, -time.Second} {
+		m, err := NewReloadingManager[MyClaims](configFile, nil, WithReloadInterval(interval))
+		require.NoError(t, err)
+		assert.Equal(t, defaultReloadInterval, m.interval)
+	}
+}
+
+func TestNewReloadingManager_BadConfig(t *testing.T) {
+	_, err := NewReloadingManager[MyClaims](filepath.Join(t.TempDir(), "jwt.json"), nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReloadingManager_Watch(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	configFile := filepath.Join(dir, "jwt.yaml")
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 1"), 0o600))
+	require.NoError(t, os.WriteFile(configFile,
+		[]byte("signing_key:\n  file: "+keyFile+"\nexpire: 1h\n"), 0o600))
+	m, err := NewReloadingManager[MyClaims](configFile,
+		[]Option[MyClaims, *MyClaims]{WithIssuer[MyClaims]("issuer")},
+		WithReloadInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- m.Watch(ctx)
+	}()
+
+	// 重新加载期间并发地生成与校验 token
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				token, err := m.GenerateToken(MyClaims{Uid: 1})
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, err = m.VerifyToken(token)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	require.NoError(t, os.WriteFile(keyFile, []byte("key 2"), 0o600))
+	assert.Eventually(t, func() bool {
+		return m.Manager().EncryptionKey == "key 2"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "issuer", m.Manager().Issuer)
+	wg.Wait()
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestReloadingManager_Conformance(t *testing.T) {
+	tokentest.RunManagerConformance(t, func(t *testing.T) tokentest.Fixture[MyClaims] {
+		configFile := filepath.Join(t.TempDir(), "jwt.json")
+		writeReloadConfig(t, configFile, Config{
+			SigningKey: KeySource{Inline: encryptionKey},
+			Expire:     Duration(defaultExpire),
+		})
+		now := nowTime
+		m, err := NewReloadingManager[MyClaims](configFile, []Option[MyClaims, *MyClaims]{
+			WithTimeFunc[MyClaims](func() time.Time { return now }),
+		})
+		require.NoError(t, err)
+		return tokentest.Fixture[MyClaims]{
+			Manager: m,
+			Claims:  func(i int) MyClaims { return MyClaims{Uid: int64(i + 1)} },
+			Equal:   func(want, got MyClaims) bool { return want.Uid == got.Uid },
+			Advance: func(d time.Duration) { now = now.Add(d) },
+			TTL:     defaultExpire,
+
+			ErrExpired:   jwt.ErrTokenExpired,
+			ErrSignature: jwt.ErrTokenSignatureInvalid,
+			ErrMalformed: jwt.ErrTokenMalformed,
+		}
+	})
+}
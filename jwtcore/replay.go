@@ -0,0 +1,55 @@
+package jwtcore
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayCache 记录已使用的 jti, 用于检测重放.
+type ReplayCache interface {
+	// Use 记录 jti 直到 expiresAt. jti 已被使用时返回 false.
+	Use(jti string, expiresAt time.Time) bool
+}
+
+// MemoryReplayCache 是基于内存的 ReplayCache.
+// 过期的 jti 会在写入时定期清理.
+type MemoryReplayCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+	timeFunc  func() time.Time
+}
+
+// replaySweepInterval 清理过期 jti 的间隔.
+const replaySweepInterval = time.Minute
+
+// NewMemoryReplayCache 创建基于内存的 ReplayCache.
+// timeFunc 为 nil 时使用 time.Now.
+func NewMemoryReplayCache(timeFunc func() time.Time) *MemoryReplayCache {
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	return &MemoryReplayCache{
+		seen:     make(map[string]time.Time),
+		timeFunc: timeFunc,
+	}
+}
+
+func (c *MemoryReplayCache) Use(jti string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.timeFunc()
+	if now.Sub(c.lastSweep) >= replaySweepInterval {
+		for k, exp := range c.seen {
+			if !now.Before(exp) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	if exp, ok := c.seen[jti]; ok && now.Before(exp) {
+		return false
+	}
+	c.seen[jti] = expiresAt
+	return true
+}
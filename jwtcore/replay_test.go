@@ -0,0 +1,22 @@
+package jwtcore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryReplayCache_Use(t *testing.T) {
+	now := nowTime
+	c := NewMemoryReplayCache(func() time.Time { return now })
+	assert.True(t, c.Use("1", now.Add(time.Minute)))
+	assert.False(t, c.Use("1", now.Add(time.Minute)))
+	assert.True(t, c.Use("2", now.Add(2*time.Minute)))
+
+	// jti 过期后可以再次使用.
+	now = now.Add(90 * time.Second)
+	assert.True(t, c.Use("1", now.Add(time.Minute)))
+	assert.False(t, c.Use("2", now.Add(time.Minute)))
+	assert.Len(t, c.seen, 2)
+}
@@ -0,0 +1,387 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// SDTag 是标记可选择性披露字段的结构体标签, 例如 `sd:"true"`.
+	SDTag = "sd"
+	// sdAlgorithm 是计算披露摘要的哈希算法.
+	sdAlgorithm = "sha-256"
+	// sdSeparator 是 SD-JWT 各部分的分隔符.
+	sdSeparator = "~"
+	// keyBindingType 是 Key Binding JWT 头部的 typ.
+	keyBindingType = "kb+jwt"
+	// keyBindingMaxAge 是 Key Binding JWT 的最长有效时间.
+	keyBindingMaxAge = 5 * time.Minute
+)
+
+// KeyBindingClaims 是 Key Binding JWT 的 claims.
+// See https://datatracker.ietf.org/doc/html/draft-ietf-oauth-selective-disclosure-jwt#section-4.3
+type KeyBindingClaims struct {
+	// the `sd_hash` claim. 不含 Key Binding JWT 的 SD-JWT 的哈希值.
+	SDHash string `json:"sd_hash"`
+
+	// the `nonce` claim.
+	Nonce string `json:"nonce"`
+
+	RegisteredClaims
+}
+
+// SDJWT 签发与校验 Selective Disclosure JWT.
+// 使用 SDTag 标记 claims 中可选择性披露的顶层字段, 持有者可以只披露其中的一部分.
+// See https://datatracker.ietf.org/doc/html/draft-ietf-oauth-selective-disclosure-jwt
+type SDJWT[T jwt.Claims, PT Claims[T]] struct {
+	manager *TokenManager[T, PT]
+}
+
+// NewSDJWT 使用 jwt 管理器创建 SDJWT.
+func NewSDJWT[T jwt.Claims, PT Claims[T]](manager *TokenManager[T, PT]) *SDJWT[T, PT] {
+	return &SDJWT[T, PT]{manager: manager}
+}
+
+// Issue 签发 SD-JWT, 返回包含全部披露的 `<jwt>~<disclosure>~...~`.
+// holderKey 不为 nil 时, 将持有者公钥放入 cnf.jwk, 持有者出示时需要附带 Key Binding JWT.
+func (s *SDJWT[T, PT]) Issue(clm T, holderKey *JSONWebKey) (string, error) {
+	return s.IssueContext(context.Background(), clm, holderKey)
+}
+
+// IssueContext 与 Issue 相同, ctx 会传递给 Signer.
+func (s *SDJWT[T, PT]) IssueContext(ctx context.Context, clm T, holderKey *JSONWebKey) (string, error) {
+	s.manager.fillClaims(PT(&clm))
+	b, err := json.Marshal(clm)
+	if err != nil {
+		return "", err
+	}
+	payload := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(b, &payload); err != nil {
+		return "", err
+	}
+
+	var digests []string
+	var disclosures []string
+	for _, name := range selectiveDisclosureNames(reflect.TypeOf(clm)) {
+		value, ok := payload[name]
+		if !ok {
+			continue
+		}
+		disclosure, err := newDisclosure(name, value)
+		if err != nil {
+			return "", err
+		}
+		delete(payload, name)
+		disclosures = append(disclosures, disclosure)
+		digests = append(digests, disclosureDigest(disclosure))
+	}
+	// 排序以隐藏字段的原始顺序.
+	sort.Strings(digests)
+
+	claims := make(jwt.MapClaims, len(payload)+3)
+	for k, v := range payload {
+		claims[k] = v
+	}
+	claims["_sd"] = digests
+	claims["_sd_alg"] = sdAlgorithm
+	if holderKey != nil {
+		claims["cnf"] = map[string]any{"jwk": holderKey}
+	}
+	token, err := s.manager.signClaims(ctx, claims)
+	if s.manager.auditLogger != nil {
+		alg, kid := s.manager.signerInfo()
+		auditIssued(ctx, s.manager.auditLogger, alg, kid, clm, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append([]string{token}, disclosures...), sdSeparator) + sdSeparator, nil
+}
+
+// Verify 校验 SD-JWT 并根据出示的披露重建 claims.
+// 带有 Key Binding JWT 时只校验其签名与 sd_hash.
+func (s *SDJWT[T, PT]) Verify(presentation string) (T, error) {
+	return s.auditVerify(presentation, "", "", false)
+}
+
+// VerifyWithKeyBinding 校验 SD-JWT 与 Key Binding JWT, 并根据出示的披露重建 claims.
+// Key Binding JWT 的 aud 与 nonce 必须与传入的值一致.
+func (s *SDJWT[T, PT]) VerifyWithKeyBinding(presentation, aud, nonce string) (T, error) {
+	return s.auditVerify(presentation, aud, nonce, true)
+}
+
+// auditVerify 校验 SD-JWT, jwt 管理器设置了 WithAuditLogger 时记录审计日志.
+func (s *SDJWT[T, PT]) auditVerify(presentation, aud, nonce string,
+	requireKeyBinding bool) (T, error) {
+	if s.manager.auditLogger == nil {
+		return s.verify(presentation, aud, nonce, requireKeyBinding, nil)
+	}
+	var info tokenInfo
+	clm, err := s.verify(presentation, aud, nonce, requireKeyBinding, &info)
+	auditVerified(context.Background(), s.manager.auditLogger, presentation, info.alg, info.kid, clm, err)
+	return clm, err
+}
+
+func (s *SDJWT[T, PT]) verify(presentation, aud, nonce string,
+	requireKeyBinding bool, info *tokenInfo) (T, error) {
+	var zeroClm T
+	parts := strings.Split(presentation, sdSeparator)
+	if len(parts) < 2 {
+		return zeroClm, fmt.Errorf("%w: 格式错误", ErrInvalidSDJWT)
+	}
+	issuerJWT, disclosures, kbJWT := parts[0], parts[1:len(parts)-1], parts[len(parts)-1]
+
+	claims := jwt.MapClaims{}
+	token, err := s.manager.parseClaims(issuerJWT, claims, info)
+	if err != nil {
+		return zeroClm, err
+	}
+	payload := make(map[string]json.RawMessage)
+	if err = decodeSegment(strings.Split(token.Raw, ".")[1], &payload); err != nil {
+		return zeroClm, fmt.Errorf("%w: %w", ErrInvalidSDJWT, err)
+	}
+	if err = resolveDisclosures(payload, disclosures); err != nil {
+		return zeroClm, fmt.Errorf("%w: %w", ErrInvalidSDJWT, err)
+	}
+
+	var kb KeyBindingClaims
+	cnf, hasCnf := payload["cnf"]
+	switch {
+	case kbJWT != "":
+		var holder struct {
+			JWK *JSONWebKey `json:"jwk"`
+		}
+		if !hasCnf || json.Unmarshal(cnf, &holder) != nil || holder.JWK == nil {
+			return zeroClm, fmt.Errorf("%w: 缺少 cnf.jwk", ErrInvalidSDJWT)
+		}
+		sdJWT := strings.TrimSuffix(presentation, kbJWT)
+		if kb, err = s.verifyKeyBinding(kbJWT, *holder.JWK, sdJWT); err != nil {
+			return zeroClm, fmt.Errorf("%w: %w", ErrInvalidSDJWT, err)
+		}
+	case requireKeyBinding:
+		return zeroClm, fmt.Errorf("%w: 缺少 Key Binding JWT", ErrInvalidSDJWT)
+	}
+	if requireKeyBinding && (!containsAudience(kb.Audience, aud) || kb.Nonce != nonce) {
+		return zeroClm, fmt.Errorf("%w: Key Binding JWT 的 aud 或 nonce 不匹配", ErrInvalidSDJWT)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return zeroClm, err
+	}
+	clm := zeroClm
+	if err = json.Unmarshal(b, &clm); err != nil {
+		return zeroClm, fmt.Errorf("%w: %w", ErrInvalidSDJWT, err)
+	}
+	// 必需的 claims 与 Validator 作用于披露后的 claims
+	if errs := s.manager.validateContent(payload, clm); len(errs) > 0 {
+		return zeroClm, fmt.Errorf("验证失败: %w", &ValidationError{Errors: errs})
+	}
+	return clm, nil
+}
+
+// verifyKeyBinding 校验 Key Binding JWT 的签名、iat 与 sd_hash.
+func (s *SDJWT[T, PT]) verifyKeyBinding(kbJWT string, holderKey JSONWebKey,
+	sdJWT string) (KeyBindingClaims, error) {
+	var kb KeyBindingClaims
+	_, err := jwt.ParseWithClaims(kbJWT, &kb,
+		func(t *jwt.Token) (interface{}, error) {
+			if typ, _ := t.Header["typ"].(string); typ != keyBindingType {
+				return nil, fmt.Errorf("typ 错误: %q", typ)
+			}
+			return holderKey.PublicKey()
+		},
+		jwt.WithValidMethods(asymmetricAlgorithms),
+		jwt.WithTimeFunc(s.manager.timeFunc),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return KeyBindingClaims{}, err
+	}
+	if kb.IssuedAt == nil || kb.IssuedAt.Add(keyBindingMaxAge).Before(s.manager.timeFunc()) {
+		return KeyBindingClaims{}, fmt.Errorf("iat 超出有效范围")
+	}
+	if kb.SDHash != sdHash(sdJWT) {
+		return KeyBindingClaims{}, fmt.Errorf("sd_hash 不匹配")
+	}
+	return kb, nil
+}
+
+// SDJWTPresent 从签发的 SD-JWT 中选择需要出示的披露.
+// 只保留 names 中列出的字段, 返回 `<jwt>~<disclosure>~...~`.
+func SDJWTPresent(sdJWT string, names ...string) (string, error) {
+	parts := strings.Split(strings.TrimSuffix(sdJWT, sdSeparator), sdSeparator)
+	reveal := make(map[string]bool, len(names))
+	for _, name := range names {
+		reveal[name] = true
+	}
+	kept := []string{parts[0]}
+	for _, disclosure := range parts[1:] {
+		name, _, err := parseDisclosure(disclosure)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidSDJWT, err)
+		}
+		if reveal[name] {
+			kept = append(kept, disclosure)
+		}
+	}
+	return strings.Join(kept, sdSeparator) + sdSeparator, nil
+}
+
+// SDJWTAddKeyBinding 使用持有者私钥签发 Key Binding JWT 并附加到出示的 SD-JWT 后.
+func SDJWTAddKeyBinding(presentation string, method jwt.SigningMethod, key crypto.Signer,
+	aud, nonce string, iat time.Time) (string, error) {
+	kb := jwt.NewWithClaims(method, KeyBindingClaims{
+		SDHash: sdHash(presentation),
+		Nonce:  nonce,
+		RegisteredClaims: RegisteredClaims{
+			Audience: jwt.ClaimStrings{aud},
+			IssuedAt: jwt.NewNumericDate(iat),
+		},
+	})
+	kb.Header["typ"] = keyBindingType
+	s, err := kb.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return presentation + s, nil
+}
+
+// resolveDisclosures 校验披露并将其还原到 payload 中.
+// 每个披露必须唯一且摘要出现在 _sd 中, 否则认为被篡改.
+func resolveDisclosures(payload map[string]json.RawMessage, disclosures []string) error {
+	var alg string
+	if raw, ok := payload["_sd_alg"]; ok {
+		if err := json.Unmarshal(raw, &alg); err != nil {
+			return err
+		}
+	}
+	if alg != sdAlgorithm {
+		return fmt.Errorf("不支持的 _sd_alg: %q", alg)
+	}
+	var digests []string
+	if raw, ok := payload["_sd"]; ok {
+		if err := json.Unmarshal(raw, &digests); err != nil {
+			return err
+		}
+	}
+	expected := make(map[string]bool, len(digests))
+	for _, d := range digests {
+		expected[d] = true
+	}
+	delete(payload, "_sd")
+	delete(payload, "_sd_alg")
+
+	used := make(map[string]bool, len(disclosures))
+	for _, disclosure := range disclosures {
+		digest := disclosureDigest(disclosure)
+		if !expected[digest] {
+			return fmt.Errorf("披露的摘要不在 _sd 中")
+		}
+		if used[digest] {
+			return fmt.Errorf("披露重复")
+		}
+		used[digest] = true
+		name, value, err := parseDisclosure(disclosure)
+		if err != nil {
+			return err
+		}
+		if _, ok := payload[name]; ok || name == "_sd" || name == "..." {
+			return fmt.Errorf("披露的字段 %q 已存在", name)
+		}
+		payload[name] = value
+	}
+	return nil
+}
+
+// newDisclosure 生成加盐的披露 base64url([salt, name, value]).
+func newDisclosure(name string, value json.RawMessage) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal([]any{encodeBase64(salt), name, value})
+	if err != nil {
+		return "", err
+	}
+	return encodeBase64(b), nil
+}
+
+// parseDisclosure 解析披露, 返回字段名与字段值.
+func parseDisclosure(disclosure string) (string, json.RawMessage, error) {
+	var arr []json.RawMessage
+	if err := decodeSegment(disclosure, &arr); err != nil {
+		return "", nil, err
+	}
+	if len(arr) != 3 {
+		return "", nil, fmt.Errorf("披露格式错误")
+	}
+	var salt, name string
+	if err := json.Unmarshal(arr[0], &salt); err != nil {
+		return "", nil, err
+	}
+	if err := json.Unmarshal(arr[1], &name); err != nil {
+		return "", nil, err
+	}
+	return name, arr[2], nil
+}
+
+func disclosureDigest(disclosure string) string {
+	sum := sha256.Sum256([]byte(disclosure))
+	return encodeBase64(sum[:])
+}
+
+func sdHash(sdJWT string) string {
+	sum := sha256.Sum256([]byte(sdJWT))
+	return encodeBase64(sum[:])
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := decodeBase64(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// selectiveDisclosureNames 返回结构体中标记了 SDTag 的字段的 json 名称.
+// 与 encoding/json 一致, 匿名嵌入且没有 json 名称的结构体字段会被展开.
+func selectiveDisclosureNames(typ reflect.Type) []string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			names = append(names, selectiveDisclosureNames(field.Type)...)
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get(SDTag) != "true" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,275 @@
+package jwtcore
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Credential struct {
+	Uid   int64  `json:"uid,omitempty"`
+	Name  string `json:"name,omitempty" sd:"true"`
+	Email string `json:"email,omitempty" sd:"true"`
+	Age   int    `json:"age,omitempty" sd:"true"`
+	RegisteredClaims
+}
+
+func TestSDJWT_Verify(t *testing.T) {
+	m := NewTokenManager[Credential](encryptionKey, defaultExpire,
+		WithTimeFunc[Credential](func() time.Time { return nowTime }),
+		WithAddParserOption[Credential](jwt.WithTimeFunc(func() time.Time { return nowTime })),
+	)
+	sd := NewSDJWT(m)
+	issued, err := sd.Issue(Credential{Uid: 1, Name: "foo", Email: "foo@example.com", Age: 18}, nil)
+	require.NoError(t, err)
+	parts := strings.Split(issued, sdSeparator)
+	require.Len(t, parts, 5)
+	assert.NotContains(t, parts[0], encodeBase64([]byte("foo@example.com")))
+
+	tests := []struct {
+		name         string
+		presentation func() string
+		want         Credential
+		wantErr      error
+	}{
+		{
+			name:         "disclose_all",
+			presentation: func() string { return issued },
+			want:         Credential{Uid: 1, Name: "foo", Email: "foo@example.com", Age: 18},
+		},
+		{
+			name: "disclose_name",
+			presentation: func() string {
+				s, err := SDJWTPresent(issued, "name")
+				require.NoError(t, err)
+				return s
+			},
+			want: Credential{Uid: 1, Name: "foo"},
+		},
+		{
+			name: "disclose_none",
+			presentation: func() string {
+				s, err := SDJWTPresent(issued)
+				require.NoError(t, err)
+				return s
+			},
+			want: Credential{Uid: 1},
+		},
+		{
+			name: "tampered_disclosure",
+			presentation: func() string {
+				d, err := newDisclosure("age", json.RawMessage("21"))
+				require.NoError(t, err)
+				return parts[0] + sdSeparator + d + sdSeparator
+			},
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name: "duplicated_disclosure",
+			presentation: func() string {
+				return strings.Join([]string{parts[0], parts[1], parts[1], ""}, sdSeparator)
+			},
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name: "key_binding_without_cnf",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(issued, jwt.SigningMethodES256, ecPrivateKey,
+					"verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				return s
+			},
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name:         "malformed",
+			presentation: func() string { return parts[0] },
+			wantErr:      ErrInvalidSDJWT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sd.Verify(tt.presentation())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, Credential{}, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Uid, got.Uid)
+			assert.Equal(t, tt.want.Name, got.Name)
+			assert.Equal(t, tt.want.Email, got.Email)
+			assert.Equal(t, tt.want.Age, got.Age)
+			assert.Equal(t, jwt.NewNumericDate(nowTime), got.IssuedAt)
+		})
+	}
+}
+
+func TestSDJWT_BadSignature(t *testing.T) {
+	sd := NewSDJWT(NewTokenManager[Credential](encryptionKey, defaultExpire))
+	issued, err := sd.Issue(Credential{Uid: 1, Name: "foo"}, nil)
+	require.NoError(t, err)
+	_, err = NewSDJWT(NewTokenManager[Credential]("another key", defaultExpire)).Verify(issued)
+	assert.Error(t, err)
+}
+
+func TestSDJWT_VerifyWithKeyBinding(t *testing.T) {
+	m := NewTokenManager[Credential](rsaPrivateKeyPEM, defaultExpire,
+		WithMethod[Credential](jwt.SigningMethodRS256),
+		WithDecryptKey[Credential](rsaPublicKeyPEM),
+		WithTimeFunc[Credential](func() time.Time { return nowTime }),
+		WithAddParserOption[Credential](jwt.WithTimeFunc(func() time.Time { return nowTime })),
+	)
+	sd := NewSDJWT(m)
+	holderJWK, err := NewJSONWebKey(&ecPrivateKey.PublicKey, "", "")
+	require.NoError(t, err)
+	issued, err := sd.Issue(Credential{Uid: 1, Name: "foo", Email: "foo@example.com"}, &holderJWK)
+	require.NoError(t, err)
+	presentation, err := SDJWTPresent(issued, "email")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		presentation func() string
+		aud          string
+		nonce        string
+		wantErr      error
+	}{
+		{
+			name: "normal",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(presentation, jwt.SigningMethodES256,
+					ecPrivateKey, "verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				return s
+			},
+			aud:   "verifier",
+			nonce: "nonce",
+		},
+		{
+			name:         "missing_key_binding",
+			presentation: func() string { return presentation },
+			aud:          "verifier",
+			nonce:        "nonce",
+			wantErr:      ErrInvalidSDJWT,
+		},
+		{
+			name: "bad_nonce",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(presentation, jwt.SigningMethodES256,
+					ecPrivateKey, "verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				return s
+			},
+			aud:     "verifier",
+			nonce:   "another nonce",
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name: "bad_audience",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(presentation, jwt.SigningMethodES256,
+					ecPrivateKey, "verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				return s
+			},
+			aud:     "another verifier",
+			nonce:   "nonce",
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name: "another_holder_key",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(presentation, jwt.SigningMethodEdDSA,
+					edPrivateKey, "verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				return s
+			},
+			aud:     "verifier",
+			nonce:   "nonce",
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			// 附加 Key Binding JWT 后移除披露
+			name: "sd_hash_mismatch",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(issued, jwt.SigningMethodES256,
+					ecPrivateKey, "verifier", "nonce", nowTime)
+				require.NoError(t, err)
+				kb := s[strings.LastIndex(s, sdSeparator)+1:]
+				return presentation + kb
+			},
+			aud:     "verifier",
+			nonce:   "nonce",
+			wantErr: ErrInvalidSDJWT,
+		},
+		{
+			name: "stale_key_binding",
+			presentation: func() string {
+				s, err := SDJWTAddKeyBinding(presentation, jwt.SigningMethodES256,
+					ecPrivateKey, "verifier", "nonce", nowTime.Add(-time.Hour))
+				require.NoError(t, err)
+				return s
+			},
+			aud:     "verifier",
+			nonce:   "nonce",
+			wantErr: ErrInvalidSDJWT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sd.VerifyWithKeyBinding(tt.presentation(), tt.aud, tt.nonce)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+			assert.Equal(t, "", got.Name)
+			assert.Equal(t, "foo@example.com", got.Email)
+		})
+	}
+}
+
+func TestSelectiveDisclosureNames(t *testing.T) {
+	type Nested struct {
+		Phone string `json:"phone" sd:"true"`
+	}
+	type Claims struct {
+		Name    string `sd:"true"`
+		Address string `json:"address,omitempty" sd:"true"`
+		Ignored string `json:"-" sd:"true"`
+		Plain   string `json:"plain"`
+		Nested
+		RegisteredClaims
+	}
+	assert.Equal(t, []string{"Name", "address", "phone"},
+		selectiveDisclosureNames(reflect.TypeOf(Claims{})))
+}
+
+func TestSDJWT_Verify_RequiredClaims(t *testing.T) {
+	m := NewTokenManager[Credential](encryptionKey, defaultExpire,
+		WithRequiredClaims[Credential]("email"),
+	)
+	sd := NewSDJWT(m)
+	issued, err := sd.Issue(Credential{Uid: 1, Name: "foo", Email: "foo@example.com"}, nil)
+	require.NoError(t, err)
+
+	// 必需的 claim 通过披露提供
+	presentation, err := SDJWTPresent(issued, "email")
+	require.NoError(t, err)
+	got, err := sd.Verify(presentation)
+	require.NoError(t, err)
+	assert.Equal(t, "foo@example.com", got.Email)
+
+	presentation, err = SDJWTPresent(issued, "name")
+	require.NoError(t, err)
+	_, err = sd.Verify(presentation)
+	assert.ErrorIs(t, err, ErrClaimMissing)
+}
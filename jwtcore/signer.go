@@ -0,0 +1,192 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer 对 jwt 的签名输入进行签名.
+// 私钥可以保存在 KMS/HSM 中, 不需要加载到应用的内存.
+type Signer interface {
+	// Sign 对签名输入 `base64url(header).base64url(claims)` 进行签名, 返回签名的原始字节.
+	Sign(ctx context.Context, signingInput string) ([]byte, error)
+	// Algorithm 返回 jwt 头部的 alg.
+	Algorithm() string
+	// KeyID 返回 jwt 头部的 kid, 为空时不设置.
+	KeyID() string
+}
+
+// SignError 是 Signer 签名失败时返回的错误.
+type SignError struct {
+	Algorithm string
+	KeyID     string
+	Err       error
+}
+
+func (e *SignError) Error() string {
+	return fmt.Sprintf("jwtcore: 签名失败 (alg=%s, kid=%s): %v", e.Algorithm, e.KeyID, e.Err)
+}
+
+func (e *SignError) Unwrap() error {
+	return e.Err
+}
+
+// keySigner 使用 jwt.SigningMethod 与内存中的密钥进行签名.
+type keySigner struct {
+	method jwt.SigningMethod
+	key    any
+	kid    string
+}
+
+// NewKeySigner 使用 jwt.SigningMethod 与内存中的密钥创建 Signer.
+// key 的类型与 jwt.SigningMethod.Sign 的要求一致, 例如 HMAC 为 []byte, RS256 为 *rsa.PrivateKey.
+func NewKeySigner(method jwt.SigningMethod, key any, kid string) Signer {
+	return &keySigner{method: method, key: key, kid: kid}
+}
+
+func (s *keySigner) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.method.Sign(signingInput, s.key)
+}
+
+func (s *keySigner) Algorithm() string {
+	return s.method.Alg()
+}
+
+func (s *keySigner) KeyID() string {
+	return s.kid
+}
+
+// pemSigner 在签名时根据签名方式解析字符串密钥, 是 TokenManager 默认的 Signer.
+type pemSigner struct {
+	method jwt.SigningMethod
+	key    string
+	keys   *sync.Map // 解析后的签名密钥, 为 nil 时不缓存
+}
+
+func (s *pemSigner) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(s.method, key, "").Sign(ctx, signingInput)
+}
+
+// signingKey 返回解析后的签名密钥, 解析成功的结果会被缓存.
+func (s *pemSigner) signingKey() (any, error) {
+	if s.keys == nil {
+		return parseSigningKey(s.method, s.key)
+	}
+	id := verifyKeyID{alg: s.method.Alg(), key: s.key}
+	if key, ok := s.keys.Load(id); ok {
+		return key, nil
+	}
+	key, err := parseSigningKey(s.method, s.key)
+	if err != nil {
+		return nil, err
+	}
+	s.keys.Store(id, key)
+	return key, nil
+}
+
+func (s *pemSigner) Algorithm() string {
+	return s.method.Alg()
+}
+
+func (s *pemSigner) KeyID() string {
+	return ""
+}
+
+// cryptoSigner 使用 crypto.Signer 进行签名.
+type cryptoSigner struct {
+	signer crypto.Signer
+	method jwt.SigningMethod
+	hash   crypto.Hash
+	opts   crypto.SignerOpts
+	kid    string
+}
+
+// NewCryptoSigner 使用 crypto.Signer 创建 Signer.
+// 支持 RSA (RS*/PS*), ECDSA (ES*) 与 Ed25519 (EdDSA) 签名方式,
+// crypto.Signer 可以由 KMS/HSM 的客户端实现.
+func NewCryptoSigner(signer crypto.Signer, method jwt.SigningMethod, kid string) (Signer, error) {
+	s := &cryptoSigner{signer: signer, method: method, kid: kid}
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSAPSS:
+		s.hash = m.Hash
+		s.opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: m.Hash}
+	case *jwt.SigningMethodRSA:
+		s.hash, s.opts = m.Hash, m.Hash
+	case *jwt.SigningMethodECDSA:
+		s.hash, s.opts = m.Hash, m.Hash
+	case *jwt.SigningMethodEd25519:
+		s.opts = crypto.Hash(0)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, method.Alg())
+	}
+	switch signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return s, nil
+}
+
+func (s *cryptoSigner) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	digest := []byte(signingInput)
+	if s.hash != 0 {
+		if !s.hash.Available() {
+			return nil, jwt.ErrHashUnavailable
+		}
+		h := s.hash.New()
+		h.Write(digest)
+		digest = h.Sum(nil)
+	}
+	sig, err := s.signer.Sign(rand.Reader, digest, s.opts)
+	if err != nil {
+		return nil, err
+	}
+	if pub, ok := s.signer.Public().(*ecdsa.PublicKey); ok {
+		// crypto.Signer 返回 ASN.1 DER 编码的签名, JWS 需要 R || S.
+		return ecdsaRawSignature(sig, pub)
+	}
+	return sig, nil
+}
+
+func (s *cryptoSigner) Algorithm() string {
+	return s.method.Alg()
+}
+
+func (s *cryptoSigner) KeyID() string {
+	return s.kid
+}
+
+// ecdsaRawSignature 将 ASN.1 DER 编码的 ECDSA 签名转换为定长的 R || S.
+func ecdsaRawSignature(der []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, err
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
@@ -0,0 +1,226 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCryptoSigner(t *testing.T) {
+	tests := []struct {
+		name      string
+		signer    crypto.Signer
+		method    jwt.SigningMethod
+		publicKey string
+		wantErr   error
+	}{
+		{
+			name:      "rs256",
+			signer:    rsaPrivateKey,
+			method:    jwt.SigningMethodRS256,
+			publicKey: rsaPublicKeyPEM,
+		},
+		{
+			name:      "ps384",
+			signer:    rsaPrivateKey,
+			method:    jwt.SigningMethodPS384,
+			publicKey: rsaPublicKeyPEM,
+		},
+		{
+			name:      "es256",
+			signer:    ecPrivateKey,
+			method:    jwt.SigningMethodES256,
+			publicKey: ecPublicKeyPEM,
+		},
+		{
+			name:      "eddsa",
+			signer:    edPrivateKey,
+			method:    jwt.SigningMethodEdDSA,
+			publicKey: edPublicKeyPEM,
+		},
+		{
+			name:    "hmac",
+			signer:  rsaPrivateKey,
+			method:  jwt.SigningMethodHS256,
+			wantErr: ErrUnsupportedAlgorithm,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewCryptoSigner(tt.signer, tt.method, "kms-key-1")
+			assert.ErrorIs(t, err, tt.wantErr)
+			if err != nil {
+				return
+			}
+			m := NewTokenManager[MyClaims]("", defaultExpire,
+				WithSigner[MyClaims](signer),
+				WithDecryptKey[MyClaims](tt.publicKey),
+			)
+			assert.Equal(t, tt.method, m.Method)
+			token, err := m.GenerateToken(MyClaims{Uid: 1})
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &MyClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, "kms-key-1", parsed.Header["kid"])
+			assert.Equal(t, tt.method.Alg(), parsed.Header["alg"])
+			got, err := m.VerifyToken(token)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestNewKeySigner(t *testing.T) {
+	m := NewTokenManager[MyClaims]("", defaultExpire,
+		WithSigner[MyClaims](NewKeySigner(jwt.SigningMethodHS256, []byte(encryptionKey), "")),
+		WithDecryptKey[MyClaims](encryptionKey),
+		WithTimeFunc[MyClaims](func() time.Time { return nowTime }),
+	)
+	token, err := m.GenerateToken(defaultClaims)
+	require.NoError(t, err)
+	// 与使用 EncryptionKey 签名的结果一致.
+	want, err := defaultManager.GenerateToken(defaultClaims)
+	require.NoError(t, err)
+	assert.Equal(t, want, token)
+}
+
+type errSigner struct{}
+
+func (errSigner) Sign(context.Context, string) ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+func (errSigner) Algorithm() string { return "RS256" }
+
+func (errSigner) KeyID() string { return "broken" }
+
+func TestTokenManager_GenerateToken_SignError(t *testing.T) {
+	m := NewTokenManager[MyClaims]("", defaultExpire, WithSigner[MyClaims](errSigner{}))
+	token, err := m.GenerateToken(MyClaims{Uid: 1})
+	assert.Equal(t, "", token)
+	var signErr *SignError
+	require.True(t, errors.As(err, &signErr))
+	assert.Equal(t, "RS256", signErr.Algorithm)
+	assert.Equal(t, "broken", signErr.KeyID)
+	assert.True(t, strings.HasSuffix(err.Error(), "boom"))
+}
+
+type ctxKey struct{}
+
+// ctxSigner 记录传入 Sign 的 ctx.
+type ctxSigner struct {
+	Signer
+	ctx context.Context
+}
+
+func (s *ctxSigner) Sign(ctx context.Context, signingInput string) ([]byte, error) {
+	s.ctx = ctx
+	return s.Signer.Sign(ctx, signingInput)
+}
+
+func TestTokenManager_GenerateTokenContext_Signer(t *testing.T) {
+	signer := &ctxSigner{Signer: NewKeySigner(jwt.SigningMethodHS256, []byte(encryptionKey), "")}
+	sd := NewSDJWT(NewTokenManager[MyClaims]("", defaultExpire, WithSigner[MyClaims](signer)))
+	tests := []struct {
+		name     string
+		generate func(ctx context.Context) (string, error)
+	}{
+		{
+			name: "token",
+			generate: func(ctx context.Context) (string, error) {
+				return sd.manager.GenerateTokenContext(ctx, MyClaims{Uid: 1})
+			},
+		},
+		{
+			name: "observed",
+			generate: func(ctx context.Context) (string, error) {
+				return sd.manager.WithOptions(WithObserver[MyClaims](ObserverFuncs{})).
+					GenerateTokenContext(ctx, MyClaims{Uid: 1})
+			},
+		},
+		{
+			name: "sd_jwt",
+			generate: func(ctx context.Context) (string, error) {
+				return sd.IssueContext(ctx, MyClaims{Uid: 1}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxKey{}, tt.name)
+			_, err := tt.generate(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.name, signer.ctx.Value(ctxKey{}))
+
+			// ctx 已取消时不签发 token
+			signer.ctx = nil
+			ctx, cancel := context.WithCancel(ctx)
+			cancel()
+			token, err := tt.generate(ctx)
+			assert.Equal(t, "", token)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Equal(t, CategoryCanceled, ErrorCategoryOf(err))
+			assert.Nil(t, signer.ctx)
+		})
+	}
+}
+
+func TestPemSigner_signingKey(t *testing.T) {
+	m := NewTokenManager[MyClaims](ecPrivateKeyPEM, defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodES256),
+		WithDecryptKey[MyClaims](ecPublicKeyPEM),
+	)
+	key, err := m.tokenSigner().(*pemSigner).signingKey()
+	require.NoError(t, err)
+	cached, err := m.tokenSigner().(*pemSigner).signingKey()
+	require.NoError(t, err)
+	assert.Same(t, key, cached)
+
+	// 修改 EncryptionKey 后重新解析
+	m.EncryptionKey = rsaPrivateKeyPEM
+	_, err = m.tokenSigner().(*pemSigner).signingKey()
+	assert.ErrorIs(t, err, jwt.ErrNotECPrivateKey)
+	m.Method = jwt.SigningMethodRS256
+	key, err = m.tokenSigner().(*pemSigner).signingKey()
+	require.NoError(t, err)
+	assert.True(t, rsaPrivateKey.Equal(key))
+
+	// 没有缓存时每次都解析
+	signer := &pemSigner{method: jwt.SigningMethodES256, key: ecPrivateKeyPEM}
+	key, err = signer.signingKey()
+	require.NoError(t, err)
+	cached, err = signer.signingKey()
+	require.NoError(t, err)
+	assert.NotSame(t, key, cached)
+}
+
+func BenchmarkTokenManager_GenerateToken(b *testing.B) {
+	benchmarks := []struct {
+		name       string
+		method     jwt.SigningMethod
+		privateKey string
+	}{
+		{name: "HS256", method: jwt.SigningMethodHS256, privateKey: encryptionKey},
+		{name: "RS256", method: jwt.SigningMethodRS256, privateKey: rsaPrivateKeyPEM},
+		{name: "ES256", method: jwt.SigningMethodES256, privateKey: ecPrivateKeyPEM},
+		{name: "EdDSA", method: jwt.SigningMethodEdDSA, privateKey: edPrivateKeyPEM},
+	}
+	for _, bm := range benchmarks {
+		m := NewTokenManager[MyClaims](bm.privateKey, time.Hour, WithMethod[MyClaims](bm.method))
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := m.GenerateToken(MyClaims{Uid: 1}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package jwtcore
 
 import (
+	"context"
+	"crypto/x509"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/udugong/token"
 )
 
 // TokenManager 定义 jwt 的管理程序.
@@ -13,8 +19,32 @@ type TokenManager[T jwt.Claims, PT Claims[T]] struct {
 	DecryptKey    string             // 解密密钥
 	Method        jwt.SigningMethod  // 签名方式
 	Expire        time.Duration      // 有效期
-	timeFunc      func() time.Time   // 控制生成 jwt 的时间
+	timeFunc      func() time.Time   // 控制生成与校验 jwt 的时间
 	parserOptions []jwt.ParserOption // jwt 解析器的选项
+	signer        Signer             // 签名器, 为空时使用 EncryptionKey 签名
+
+	allowedAlgorithms []string       // 除 Method 外额外允许的签名算法
+	acceptedIssuers   []string       // 除 Issuer 外额外接受的 iss
+	audiences         []string       // 预期的 aud, 为空时不校验
+	requiredClaims    []string       // 必需的 claims
+	leeway            time.Duration  // 校验 exp/nbf/iat 时允许的时钟偏差
+	maxAge            time.Duration  // 从 iat 开始计算的最大有效期, 为 0 时不校验
+	rejectFutureIAT   bool           // 是否拒绝 iat 晚于当前时间的 token
+	validators        []Validator[T] // 签名校验通过后执行的 Validator
+	headers           map[string]any // 签发时额外设置的 JOSE 头部
+	genHeaderFn       func() map[string]any
+	expectedType      string           // 校验时要求的 typ, 为空时不校验
+	x509Roots         *x509.CertPool   // 校验 x5c 证书链的根证书
+	x509VerifyLeaf    X509LeafVerifier // 校验 x5c 的叶子证书
+	maxTokenSize      int              // token 的最大长度, 为 0 时不限制
+	maxJSONDepth      int              // 头部与 payload 的最大 JSON 嵌套深度, 为 0 时不限制
+	verifyConcurrency int              // VerifyTokens 的并发数, 为 0 时使用 GOMAXPROCS
+	validator         *jwt.Validator   // 校验 exp/nbf/iat, 由 WithOptions 创建
+	observer          Observer         // 观察签发与校验, 为 nil 时不观察
+	auditLogger       *slog.Logger     // 记录审计日志, 为 nil 时不记录
+	tracer            token.Tracer     // 创建签发与校验的 span, 为 nil 时不追踪
+	verifyKeys        *sync.Map        // 解析后的解密密钥, 键为 verifyKeyID
+	signingKeys       *sync.Map        // 解析后的签名密钥, 键为 verifyKeyID
 	ClaimsOption
 }
 
@@ -30,6 +60,9 @@ type ClaimsOption struct {
 // NewTokenManager 创建 jwt 管理器.
 // Method: 默认使用 jwt.SigningMethodHS256 对称签名方式.
 // DecryptKey: 默认与 EncryptionKey 相同.
+// 使用 RSA/ECDSA/EdDSA 签名方式时, EncryptionKey 为 PEM 编码的私钥,
+// DecryptKey 需要通过 WithDecryptKey 设置为 PEM 编码的公钥.
+// 私钥保存在 KMS/HSM 中时, 使用 WithSigner 设置签名器.
 func NewTokenManager[T jwt.Claims, PT Claims[T]](encryptionKey string,
 	expire time.Duration, options ...Option[T, PT]) *TokenManager[T, PT] {
 	manager := &TokenManager[T, PT]{
@@ -39,13 +72,54 @@ func NewTokenManager[T jwt.Claims, PT Claims[T]](encryptionKey string,
 		Method:        jwt.SigningMethodHS256,
 		timeFunc:      time.Now,
 		parserOptions: []jwt.ParserOption{},
+		verifyKeys:    &sync.Map{},
+		signingKeys:   &sync.Map{},
 	}
 	return manager.WithOptions(options...)
 }
 
 // GenerateToken 生成一个 jwt token.
 func (t *TokenManager[T, PT]) GenerateToken(clm T) (string, error) {
-	p := PT(&clm)
+	return t.GenerateTokenContext(context.Background(), clm)
+}
+
+// GenerateTokenContext 生成一个 jwt token.
+// 设置了 WithTracer 时, 签发的 span 是 ctx 中 span 的子 span.
+func (t *TokenManager[T, PT]) GenerateTokenContext(ctx context.Context, clm T) (string, error) {
+	if t.observer != nil || t.auditLogger != nil || t.tracer != nil {
+		return t.observeGenerate(ctx, clm)
+	}
+	t.fillClaims(PT(&clm))
+	return t.signClaims(ctx, clm)
+}
+
+// VerifyToken 认证 token 并返回 claims 与 error.
+func (t *TokenManager[T, PT]) VerifyToken(token string) (T, error) {
+	return t.VerifyTokenContext(context.Background(), token)
+}
+
+// VerifyTokenContext 认证 token 并返回 claims 与 error.
+// 设置了 WithTracer 时, 校验的 span 是 ctx 中 span 的子 span.
+// 绑定了持有者密钥 (cnf.jkt 或 cnf.x5t#S256) 的 token 返回 ErrTokenNotBound,
+// 需要使用 DPoP 或 MTLS 的 VerifyRequest 校验.
+func (t *TokenManager[T, PT]) VerifyTokenContext(ctx context.Context, token string) (T, error) {
+	if t.observer != nil || t.auditLogger != nil || t.tracer != nil {
+		return t.observeVerify(ctx, token)
+	}
+	var clm T
+	if err := t.verify(token, &clm, nil); err != nil {
+		var zeroClm T
+		return zeroClm, err
+	}
+	if err := checkBinding(ctx, PT(&clm)); err != nil {
+		var zeroClm T
+		return zeroClm, err
+	}
+	return clm, nil
+}
+
+// fillClaims 设置签发人、签发时间、过期时间等 claims.
+func (t *TokenManager[T, PT]) fillClaims(p PT) {
 	if t.genSubjectFn != nil {
 		p.SetSubject(t.genSubjectFn())
 	}
@@ -62,25 +136,79 @@ func (t *TokenManager[T, PT]) GenerateToken(clm T) (string, error) {
 	p.SetIssuer(t.Issuer)
 	p.SetIssuedAt(jwt.NewNumericDate(nowTime))
 	p.SetExpiresAt(jwt.NewNumericDate(nowTime.Add(t.Expire)))
-	return jwt.NewWithClaims(t.Method, clm).
-		SignedString([]byte(t.EncryptionKey))
 }
 
-// VerifyToken 认证 token 并返回 claims 与 error.
-func (t *TokenManager[T, PT]) VerifyToken(token string) (T, error) {
-	var zeroClm T
-	clm := zeroClm
-	var clmPtr any = &clm
-	withClaims, err := jwt.ParseWithClaims(token, clmPtr.(jwt.Claims),
-		func(*jwt.Token) (interface{}, error) {
-			return []byte(t.DecryptKey), nil
+// signClaims 使用 Signer 对 claims 进行签名.
+// 签名失败时返回 *SignError.
+func (t *TokenManager[T, PT]) signClaims(ctx context.Context, claims jwt.Claims) (string, error) {
+	signer := t.tokenSigner()
+	if err := ctx.Err(); err != nil {
+		return "", &SignError{Algorithm: signer.Algorithm(), KeyID: signer.KeyID(), Err: err}
+	}
+	token := jwt.NewWithClaims(t.Method, claims)
+	t.buildHeader(token.Header, signer)
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.Sign(ctx, signingString)
+	if err != nil {
+		return "", &SignError{Algorithm: signer.Algorithm(), KeyID: signer.KeyID(), Err: err}
+	}
+	return signingString + "." + token.EncodeSegment(sig), nil
+}
+
+// tokenSigner 返回签名使用的 Signer.
+func (t *TokenManager[T, PT]) tokenSigner() Signer {
+	if t.signer == nil {
+		return &pemSigner{method: t.Method, key: t.EncryptionKey, keys: t.signingKeys}
+	}
+	return t.signer
+}
+
+// parseClaims 解析 token 到 claims 并校验.
+// 签名算法不被允许时返回的错误包含 ErrAlgorithmNotAllowed.
+// info 不为 nil 时记录 token 的头部信息.
+func (t *TokenManager[T, PT]) parseClaims(token string, claims jwt.Claims,
+	info *tokenInfo) (*jwt.Token, error) {
+	if err := t.checkLimits(token); err != nil {
+		return nil, fmt.Errorf("验证失败: %w", err)
+	}
+	withClaims, err := jwt.ParseWithClaims(token, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			info.setHeader(token.Header)
+			if err := t.checkAlgorithm(token.Method); err != nil {
+				return nil, err
+			}
+			if err := t.checkType(token.Header["typ"]); err != nil {
+				return nil, err
+			}
+			return t.verifyKey(token)
 		},
-		t.parserOptions...,
+		t.verifyParserOptions()...,
 	)
 	if err != nil || !withClaims.Valid {
-		return zeroClm, fmt.Errorf("验证失败: %v", err)
+		return nil, fmt.Errorf("验证失败: %w", err)
 	}
-	return clm, nil
+	if err = t.validateClaims(withClaims.Claims, func() ([]byte, error) {
+		return decodePayload(token)
+	}); err != nil {
+		return nil, fmt.Errorf("验证失败: %w", err)
+	}
+	return withClaims, nil
+}
+
+// verifyParserOptions 返回校验时使用的 jwt.ParserOption.
+// 默认使用 timeFunc 与 leeway 校验 exp/nbf/iat, 并拒绝非规范的 base64 编码,
+// parserOptions 中的同类设置优先.
+func (t *TokenManager[T, PT]) verifyParserOptions() []jwt.ParserOption {
+	opts := make([]jwt.ParserOption, 0, len(t.parserOptions)+4)
+	opts = append(opts, jwt.WithTimeFunc(t.timeFunc), jwt.WithLeeway(t.leeway),
+		jwt.WithStrictDecoding())
+	if t.rejectFutureIAT {
+		opts = append(opts, jwt.WithIssuedAt())
+	}
+	return append(opts, t.parserOptions...)
 }
 
 func (t *TokenManager[T, PT]) WithOptions(opts ...Option[T, PT]) *TokenManager[T, PT] {
@@ -88,6 +216,7 @@ func (t *TokenManager[T, PT]) WithOptions(opts ...Option[T, PT]) *TokenManager[T
 	for _, opt := range opts {
 		opt.apply(c)
 	}
+	c.validator = jwt.NewValidator(c.verifyParserOptions()...)
 	return c
 }
 
@@ -0,0 +1,44 @@
+package jwtcore
+
+import (
+	"context"
+
+	"github.com/udugong/token"
+)
+
+// startSpan 使用 WithTracer 设置的 Tracer 创建 span, 没有设置时返回 nil.
+func (t *TokenManager[T, PT]) startSpan(ctx context.Context, name string) (context.Context, token.Span) {
+	if t.tracer == nil {
+		return ctx, nil
+	}
+	return t.tracer.Start(ctx, name)
+}
+
+// endSpan 设置 span 的属性并结束 span, 为空的属性不设置.
+func endSpan(span token.Span, alg, kid, issuer string, err error) {
+	if span == nil {
+		return
+	}
+	attrs := make([]token.Attribute, 0, 5)
+	for _, attr := range []token.Attribute{
+		{Key: token.AttrAlgorithm, Value: alg},
+		{Key: token.AttrKeyID, Value: kid},
+		{Key: token.AttrIssuer, Value: issuer},
+	} {
+		if attr.Value != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if err != nil {
+		attrs = append(attrs,
+			token.Attribute{Key: token.AttrOutcome, Value: token.OutcomeFailure},
+			token.Attribute{Key: token.AttrErrorClass, Value: string(ErrorCategoryOf(err))},
+		)
+		span.SetAttributes(attrs...)
+		span.RecordError(err)
+	} else {
+		attrs = append(attrs, token.Attribute{Key: token.AttrOutcome, Value: token.OutcomeSuccess})
+		span.SetAttributes(attrs...)
+	}
+	span.End()
+}
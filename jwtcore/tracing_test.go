@@ -0,0 +1,320 @@
+package jwtcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token"
+	"github.com/udugong/token/tokentest"
+)
+
+func TestTokenManager_Tracer(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[MyClaims](func() time.Time { return nowTime }),
+		WithIssuer[MyClaims]("https://issuer.example.com"),
+		WithKeyID[MyClaims]("k1"),
+		WithTracer[MyClaims](r),
+	)
+	var cm token.ContextManager[MyClaims] = m
+
+	ctx, parent := r.Start(context.Background(), "handler")
+	tokenString, err := cm.GenerateTokenContext(ctx, MyClaims{Uid: 1})
+	require.NoError(t, err)
+	got, err := cm.VerifyTokenContext(ctx, tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), got.Uid)
+	_, err = cm.VerifyTokenContext(ctx, tokenString+"x")
+	require.Error(t, err)
+	_, err = cm.VerifyTokenContext(ctx, "malformed")
+	require.Error(t, err)
+	parent.End()
+
+	spans := r.Spans()
+	require.Len(t, spans, 5)
+	for _, s := range spans[1:] {
+		assert.Equal(t, spans[0].ID, s.ParentID)
+		assert.True(t, s.Ended)
+	}
+	assert.Equal(t, token.SpanGenerateToken, spans[1].Name)
+	assert.Equal(t, map[string]string{
+		token.AttrAlgorithm: "HS256",
+		token.AttrKeyID:     "k1",
+		token.AttrIssuer:    "https://issuer.example.com",
+		token.AttrOutcome:   token.OutcomeSuccess,
+	}, spans[1].Attributes)
+	assert.Empty(t, spans[1].Errors)
+
+	assert.Equal(t, token.SpanVerifyToken, spans[2].Name)
+	assert.Equal(t, map[string]string{
+		token.AttrAlgorithm: "HS256",
+		token.AttrKeyID:     "k1",
+		token.AttrIssuer:    "https://issuer.example.com",
+		token.AttrOutcome:   token.OutcomeSuccess,
+	}, spans[2].Attributes)
+
+	// 校验失败时不记录未经校验的 iss
+	assert.Equal(t, map[string]string{
+		token.AttrAlgorithm:  "HS256",
+		token.AttrKeyID:      "k1",
+		token.AttrOutcome:    token.OutcomeFailure,
+		token.AttrErrorClass: string(CategorySignature),
+	}, spans[3].Attributes)
+	require.Len(t, spans[3].Errors, 1)
+	assert.ErrorIs(t, spans[3].Errors[0], jwt.ErrTokenSignatureInvalid)
+
+	assert.Equal(t, map[string]string{
+		token.AttrOutcome:    token.OutcomeFailure,
+		token.AttrErrorClass: string(CategoryMalformed),
+	}, spans[4].Attributes)
+}
+
+func TestTokenManager_Tracer_Background(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	m := defaultManager.WithOptions(WithTracer[MyClaims](r))
+	tokenString, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = m.VerifyToken(tokenString)
+	require.NoError(t, err)
+
+	spans := r.Spans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, token.SpanGenerateToken, spans[0].Name)
+	assert.Equal(t, token.SpanVerifyToken, spans[1].Name)
+	assert.Zero(t, spans[0].ParentID)
+	assert.Zero(t, spans[1].ParentID)
+}
+
+func TestVerifyTokens_Tracer(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	m := defaultManager.WithOptions(WithTracer[MyClaims](r))
+	tokenString, err := defaultManager.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	ctx, parent := r.Start(context.Background(), "batch")
+	results := m.VerifyTokens(ctx, []string{tokenString, "malformed", tokenString})
+	parent.End()
+	require.Len(t, results, 3)
+
+	spans := r.Spans()
+	// 相同的 token 只校验一次
+	require.Len(t, spans, 3)
+	for _, s := range spans[1:] {
+		assert.Equal(t, token.SpanVerifyToken, s.Name)
+		assert.Equal(t, spans[0].ID, s.ParentID)
+	}
+}
+
+func TestManagers_VerifyTokenContext(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	m := defaultManager.WithOptions(
+		WithIssuer[MyClaims]("https://a.example.com"), WithTracer[MyClaims](r))
+	tokenString, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	registry := NewRegistry[MyClaims]()
+	registry.Register("https://a.example.com", m)
+	cache := NewCachingManager[MyClaims](m,
+		WithCacheTimeFunc[MyClaims](func() time.Time { return nowTime }))
+	tests := []struct {
+		name      string
+		verify    func(ctx context.Context) error
+		wantSpans int
+	}{
+		{
+			name: "registry",
+			verify: func(ctx context.Context) error {
+				_, err := registry.VerifyTokenContext(ctx, tokenString)
+				return err
+			},
+			wantSpans: 1,
+		},
+		{
+			name: "cache_miss",
+			verify: func(ctx context.Context) error {
+				_, err := cache.VerifyTokenContext(ctx, tokenString)
+				return err
+			},
+			wantSpans: 1,
+		},
+		{
+			// 命中缓存时不校验 token
+			name: "cache_hit",
+			verify: func(ctx context.Context) error {
+				_, err := cache.VerifyTokenContext(ctx, tokenString)
+				return err
+			},
+			wantSpans: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.Reset()
+			ctx, parent := r.Start(context.Background(), "handler")
+			require.NoError(t, tt.verify(ctx))
+			parent.End()
+			spans := r.Spans()
+			require.Len(t, spans, tt.wantSpans+1)
+			for _, s := range spans[1:] {
+				assert.Equal(t, token.SpanVerifyToken, s.Name)
+				assert.Equal(t, spans[0].ID, s.ParentID)
+			}
+		})
+	}
+}
+
+func TestCachingManager_GenerateTokenContext(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	m := defaultManager.WithOptions(WithTracer[MyClaims](r))
+	ctx, parent := r.Start(context.Background(), "handler")
+
+	_, err := NewCachingManager[MyClaims](m).GenerateTokenContext(ctx, MyClaims{Uid: 1})
+	require.NoError(t, err)
+	// 没有实现 token.ContextManager 时忽略 ctx
+	_, err = NewCachingManager[MyClaims](token.Manager[MyClaims](plainManager{m})).
+		GenerateTokenContext(ctx, MyClaims{Uid: 1})
+	require.NoError(t, err)
+	parent.End()
+
+	spans := r.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, spans[0].ID, spans[1].ParentID)
+	assert.Zero(t, spans[2].ParentID)
+}
+
+// plainManager 只实现 token.Manager.
+type plainManager struct {
+	m *TokenManager[MyClaims, *MyClaims]
+}
+
+func (p plainManager) GenerateToken(clm MyClaims) (string, error) {
+	return p.m.GenerateToken(clm)
+}
+
+func (p plainManager) VerifyToken(token string) (MyClaims, error) {
+	return p.m.VerifyToken(token)
+}
+
+func TestVerifyRequest_Tracer(t *testing.T) {
+	const htu = "http://example.com/resource"
+	r := tokentest.NewSpanRecorder()
+	m := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire, WithTracer[BoundMyClaims](r))
+
+	d := NewDPoP(m)
+	clientJWK, err := NewJSONWebKey(edPublicKey, "", "")
+	require.NoError(t, err)
+	dpopToken, err := d.GenerateToken(BoundMyClaims{Uid: 1}, clientJWK)
+	require.NoError(t, err)
+	proofClaims, err := NewDPoPProofClaims(http.MethodGet, htu, dpopToken, time.Now())
+	require.NoError(t, err)
+	proof, err := SignDPoPProof(jwt.SigningMethodEdDSA, edPrivateKey, proofClaims)
+	require.NoError(t, err)
+
+	mtls := NewMTLS(m)
+	ca := newTestCertificate(t, "ca", nil)
+	clientCert := newTestCertificate(t, "client", &ca)
+	mtlsToken, err := mtls.GenerateToken(BoundMyClaims{Uid: 1}, clientCert.Leaf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		verify func(req *http.Request) error
+	}{
+		{
+			name: "dpop",
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", DPoPScheme+" "+dpopToken)
+				req.Header.Set(DPoPHeader, proof)
+			},
+			verify: func(req *http.Request) error {
+				_, err := d.VerifyRequest(req)
+				return err
+			},
+		},
+		{
+			name: "mtls",
+			setup: func(req *http.Request) {
+				req.Header.Set("Authorization", BearerScheme+" "+mtlsToken)
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert.Leaf}}
+			},
+			verify: func(req *http.Request) error {
+				_, err := mtls.VerifyRequest(req)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.Reset()
+			ctx, parent := r.Start(context.Background(), "handler")
+			req := httptest.NewRequest(http.MethodGet, htu, nil).WithContext(ctx)
+			tt.setup(req)
+			require.NoError(t, tt.verify(req))
+			parent.End()
+
+			// 校验的 span 是请求 ctx 中 span 的子 span
+			spans := r.Spans()
+			require.Len(t, spans, 2)
+			assert.Equal(t, token.SpanVerifyToken, spans[1].Name)
+			assert.Equal(t, spans[0].ID, spans[1].ParentID)
+		})
+	}
+}
+
+func TestGenerateTokenContext_Tracer(t *testing.T) {
+	r := tokentest.NewSpanRecorder()
+	bound := NewTokenManager[BoundMyClaims](encryptionKey, defaultExpire, WithTracer[BoundMyClaims](r))
+	idTokens := NewTokenManager[IDTokenClaims](encryptionKey, defaultExpire, WithTracer[IDTokenClaims](r))
+	clientJWK, err := NewJSONWebKey(edPublicKey, "", "")
+	require.NoError(t, err)
+	cert := newTestCertificate(t, "client", nil)
+
+	tests := []struct {
+		name     string
+		generate func(ctx context.Context) (string, error)
+	}{
+		{
+			name: "dpop",
+			generate: func(ctx context.Context) (string, error) {
+				return NewDPoP(bound).GenerateTokenContext(ctx, BoundMyClaims{Uid: 1}, clientJWK)
+			},
+		},
+		{
+			name: "mtls",
+			generate: func(ctx context.Context) (string, error) {
+				return NewMTLS(bound).GenerateTokenContext(ctx, BoundMyClaims{Uid: 1}, cert.Leaf)
+			},
+		},
+		{
+			name: "id_token",
+			generate: func(ctx context.Context) (string, error) {
+				return NewIDTokenIssuer(idTokens).IssueIDTokenContext(ctx, IDTokenClaims{}, "at", "code")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.Reset()
+			ctx, parent := r.Start(context.Background(), "handler")
+			_, err := tt.generate(ctx)
+			require.NoError(t, err)
+			parent.End()
+
+			// 签发的 span 是 ctx 中 span 的子 span
+			spans := r.Spans()
+			require.Len(t, spans, 2)
+			assert.Equal(t, token.SpanGenerateToken, spans[1].Name)
+			assert.Equal(t, spans[0].ID, spans[1].ParentID)
+		})
+	}
+}
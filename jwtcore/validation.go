@@ -0,0 +1,194 @@
+package jwtcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Validator 在签名校验通过后校验 claims.
+type Validator[T any] interface {
+	Validate(claims T) error
+}
+
+// ValidatorFunc wraps a func, so it satisfies the Validator interface.
+type ValidatorFunc[T any] func(claims T) error
+
+func (f ValidatorFunc[T]) Validate(claims T) error {
+	return f(claims)
+}
+
+// ValidateClaim 创建校验单个 claim 的 Validator.
+// fn 返回的错误会被包装为 *ClaimError, 用于指明校验失败的 claim.
+func ValidateClaim[T any](claim string, fn func(claims T) error) Validator[T] {
+	return ValidatorFunc[T](func(claims T) error {
+		if err := fn(claims); err != nil {
+			return &ClaimError{Claim: claim, Err: err}
+		}
+		return nil
+	})
+}
+
+// ClaimError 是单个 claim 校验失败的错误.
+type ClaimError struct {
+	Claim string
+	Err   error
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Claim, e.Err)
+}
+
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError 汇总了 claims 校验失败的所有错误.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "jwtcore: claims 校验失败: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// validateClaims 在签名校验通过后校验 claims.
+// claims 为 PT 时同时校验必需的 claims 并执行 Validator.
+// payload 返回解码后的 claims JSON, 只在设置了必需的 claims 时调用.
+func (t *TokenManager[T, PT]) validateClaims(claims jwt.Claims, payload func() ([]byte, error)) error {
+	errs := t.validateRegisteredClaims(claims)
+	if p, ok := claims.(PT); ok {
+		required, err := t.requiredPayload(payload)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, t.validateContent(required, *p)...)
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validateRegisteredClaims 校验 iss 与 aud.
+func (t *TokenManager[T, PT]) validateRegisteredClaims(claims jwt.Claims) []error {
+	var errs []error
+	if err := t.validateIssuer(claims); err != nil {
+		errs = append(errs, &ClaimError{Claim: "iss", Err: err})
+	}
+	if err := t.validateAudience(claims); err != nil {
+		errs = append(errs, &ClaimError{Claim: "aud", Err: err})
+	}
+	if err := t.validateMaxAge(claims); err != nil {
+		errs = append(errs, &ClaimError{Claim: "iat", Err: err})
+	}
+	return errs
+}
+
+// validateContent 校验必需的 claims 并执行 Validator.
+// payload 为 token 的 claims 集合, 没有设置必需的 claims 时可以为 nil.
+func (t *TokenManager[T, PT]) validateContent(payload map[string]json.RawMessage, clm T) []error {
+	var errs []error
+	for _, name := range t.requiredClaims {
+		if !claimPresent(payload[name]) {
+			errs = append(errs, &ClaimError{Claim: name, Err: ErrClaimMissing})
+		}
+	}
+	for _, v := range t.validators {
+		if err := v.Validate(clm); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// requiredPayload 在设置了必需的 claims 时解析 token 的 claims 集合.
+func (t *TokenManager[T, PT]) requiredPayload(payload func() ([]byte, error)) (map[string]json.RawMessage, error) {
+	if len(t.requiredClaims) == 0 {
+		return nil, nil
+	}
+	b, err := payload()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", jwt.ErrTokenMalformed, err)
+	}
+	required := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(b, &required); err != nil {
+		return nil, fmt.Errorf("%w: %w", jwt.ErrTokenMalformed, err)
+	}
+	return required, nil
+}
+
+// claimPresent 判断 claim 是否存在且不为 null、空字符串或空数组.
+func claimPresent(v json.RawMessage) bool {
+	switch strings.TrimSpace(string(v)) {
+	case "", "null", `""`, "[]":
+		return false
+	}
+	return true
+}
+
+// validateIssuer 校验 iss 是 Issuer 或 WithAcceptedIssuers 设置的 issuer 之一.
+func (t *TokenManager[T, PT]) validateIssuer(claims jwt.Claims) error {
+	if t.Issuer == "" && len(t.acceptedIssuers) == 0 {
+		return nil
+	}
+	iss, err := claims.GetIssuer()
+	if err != nil {
+		return err
+	}
+	if iss != "" && iss == t.Issuer {
+		return nil
+	}
+	for _, accepted := range t.acceptedIssuers {
+		if iss == accepted {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %w: %q", jwt.ErrTokenInvalidIssuer, ErrIssuerMismatch, iss)
+}
+
+// validateAudience 校验 aud 至少包含 WithAudiences 设置的 audience 之一.
+func (t *TokenManager[T, PT]) validateAudience(claims jwt.Claims) error {
+	if len(t.audiences) == 0 {
+		return nil
+	}
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return err
+	}
+	for _, expected := range t.audiences {
+		if containsAudience(aud, expected) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %w: %q", jwt.ErrTokenInvalidAudience, ErrAudienceMismatch, aud)
+}
+
+// validateMaxAge 校验从 iat 开始计算的有效期不超过 WithMaxAge 设置的最大有效期.
+func (t *TokenManager[T, PT]) validateMaxAge(claims jwt.Claims) error {
+	if t.maxAge <= 0 {
+		return nil
+	}
+	iat, err := claims.GetIssuedAt()
+	if err != nil {
+		return err
+	}
+	if iat == nil {
+		return ErrClaimMissing
+	}
+	if age := t.timeFunc().Sub(iat.Time); age > t.maxAge+t.leeway {
+		return fmt.Errorf("%w: %s", ErrTokenTooOld, age.Truncate(time.Second))
+	}
+	return nil
+}
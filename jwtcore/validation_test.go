@@ -0,0 +1,301 @@
+package jwtcore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenManager_VerifyToken_IssuerAndAudience(t *testing.T) {
+	// issue 使用指定的 iss 与 aud 签发 token.
+	issue := func(iss string, aud ...string) string {
+		m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+			WithIssuer[MyClaims](iss),
+			WithGenAudienceFunc[MyClaims](func() jwt.ClaimStrings { return aud }),
+		)
+		token, err := m.GenerateToken(MyClaims{Uid: 1})
+		require.NoError(t, err)
+		return token
+	}
+	tests := []struct {
+		name    string
+		opts    []Option[MyClaims, *MyClaims]
+		token   string
+		wantErr []error
+	}{
+		{
+			name:  "no_issuer",
+			token: issue("another issuer"),
+		},
+		{
+			name:  "issuer_matched",
+			opts:  []Option[MyClaims, *MyClaims]{WithIssuer[MyClaims]("service-a")},
+			token: issue("service-a"),
+		},
+		{
+			name:    "issuer_mismatch",
+			opts:    []Option[MyClaims, *MyClaims]{WithIssuer[MyClaims]("service-a")},
+			token:   issue("service-b"),
+			wantErr: []error{ErrIssuerMismatch, jwt.ErrTokenInvalidIssuer},
+		},
+		{
+			name:    "issuer_missing",
+			opts:    []Option[MyClaims, *MyClaims]{WithIssuer[MyClaims]("service-a")},
+			token:   issue(""),
+			wantErr: []error{ErrIssuerMismatch},
+		},
+		{
+			name: "accepted_issuer",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithIssuer[MyClaims]("service-a"),
+				WithAcceptedIssuers[MyClaims]("service-b", "service-c"),
+			},
+			token: issue("service-c"),
+		},
+		{
+			name: "accepted_issuer_without_issuer",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithAcceptedIssuers[MyClaims]("service-b"),
+			},
+			token:   issue("service-a"),
+			wantErr: []error{ErrIssuerMismatch},
+		},
+		{
+			name:  "audience_matched",
+			opts:  []Option[MyClaims, *MyClaims]{WithAudiences[MyClaims]("api-a", "api-b")},
+			token: issue("", "web", "api-b"),
+		},
+		{
+			name:    "audience_mismatch",
+			opts:    []Option[MyClaims, *MyClaims]{WithAudiences[MyClaims]("api-a")},
+			token:   issue("", "api-b"),
+			wantErr: []error{ErrAudienceMismatch, jwt.ErrTokenInvalidAudience},
+		},
+		{
+			name:    "audience_missing",
+			opts:    []Option[MyClaims, *MyClaims]{WithAudiences[MyClaims]("api-a")},
+			token:   issue(""),
+			wantErr: []error{ErrAudienceMismatch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, tt.opts...)
+			got, err := m.VerifyToken(tt.token)
+			if tt.wantErr != nil {
+				for _, want := range tt.wantErr {
+					assert.ErrorIs(t, err, want)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestTokenManager_VerifyToken_Validators(t *testing.T) {
+	issue := func(opts ...Option[MyClaims, *MyClaims]) string {
+		m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, opts...)
+		token, err := m.GenerateToken(MyClaims{Uid: 1})
+		require.NoError(t, err)
+		return token
+	}
+	uidPositive := ValidateClaim("uid", func(clm MyClaims) error {
+		if clm.Uid <= 0 {
+			return errors.New("必须大于 0")
+		}
+		return nil
+	})
+	errNotAdmin := errors.New("not admin")
+	notAdmin := ValidatorFunc[MyClaims](func(MyClaims) error { return errNotAdmin })
+	tests := []struct {
+		name        string
+		opts        []Option[MyClaims, *MyClaims]
+		token       string
+		wantClaims  []string
+		wantErrs    []error
+		wantMessage string
+	}{
+		{
+			name: "required_claims_present",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithRequiredClaims[MyClaims]("exp", "iat", "sub", "jti"),
+			},
+			token: issue(
+				WithGenSubjectFunc[MyClaims](func() string { return "user" }),
+				WithGenIDFunc[MyClaims](func() string { return "1" }),
+			),
+		},
+		{
+			name: "required_claims_missing",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithRequiredClaims[MyClaims]("exp", "sub", "jti"),
+			},
+			token:       issue(),
+			wantClaims:  []string{"sub", "jti"},
+			wantErrs:    []error{ErrClaimMissing},
+			wantMessage: "验证失败: jwtcore: claims 校验失败: sub: jwtcore: 缺少必需的 claim; jti: jwtcore: 缺少必需的 claim",
+		},
+		{
+			name: "validator_passed",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithValidators[MyClaims](uidPositive),
+			},
+			token: issue(),
+		},
+		{
+			name: "aggregated",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithIssuer[MyClaims]("service-a"),
+				WithRequiredClaims[MyClaims]("jti"),
+				WithValidators[MyClaims](uidPositive, notAdmin),
+			},
+			token:      issue(WithIssuer[MyClaims]("service-b")),
+			wantClaims: []string{"iss", "jti"},
+			wantErrs:   []error{ErrIssuerMismatch, ErrClaimMissing, errNotAdmin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, tt.opts...)
+			got, err := m.VerifyToken(tt.token)
+			if tt.wantErrs == nil {
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), got.Uid)
+				return
+			}
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr))
+			var claims []string
+			for _, e := range verr.Errors {
+				var cerr *ClaimError
+				if errors.As(e, &cerr) {
+					claims = append(claims, cerr.Claim)
+				}
+			}
+			assert.Equal(t, tt.wantClaims, claims)
+			for _, want := range tt.wantErrs {
+				assert.ErrorIs(t, err, want)
+			}
+			if tt.wantMessage != "" {
+				assert.EqualError(t, err, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestValidateClaim(t *testing.T) {
+	v := ValidateClaim("tenant_id", func(clm MyClaims) error {
+		return ErrClaimMissing
+	})
+	err := v.Validate(MyClaims{})
+	var cerr *ClaimError
+	require.True(t, errors.As(err, &cerr))
+	assert.Equal(t, "tenant_id", cerr.Claim)
+	assert.ErrorIs(t, err, ErrClaimMissing)
+	assert.EqualError(t, err, "tenant_id: jwtcore: 缺少必需的 claim")
+}
+
+func TestTokenManager_VerifyToken_TimePolicies(t *testing.T) {
+	// issue 在 nowTime + offset 签发 token.
+	issue := func(offset time.Duration) string {
+		m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+			WithTimeFunc[MyClaims](func() time.Time { return nowTime.Add(offset) }),
+		)
+		token, err := m.GenerateToken(MyClaims{Uid: 1})
+		require.NoError(t, err)
+		return token
+	}
+	tests := []struct {
+		name    string
+		opts    []Option[MyClaims, *MyClaims]
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "verify_with_time_func",
+			token: issue(0),
+		},
+		{
+			name:    "expired",
+			token:   issue(-defaultExpire - time.Second),
+			wantErr: jwt.ErrTokenExpired,
+		},
+		{
+			name:  "expired_within_leeway",
+			opts:  []Option[MyClaims, *MyClaims]{WithLeeway[MyClaims](5 * time.Second)},
+			token: issue(-defaultExpire - time.Second),
+		},
+		{
+			// 其他节点的时钟快了 3 秒
+			name:  "future_iat_allowed_by_default",
+			token: issue(3 * time.Second),
+		},
+		{
+			name:    "future_iat",
+			opts:    []Option[MyClaims, *MyClaims]{WithRejectFutureIssuedAt[MyClaims]()},
+			token:   issue(3 * time.Second),
+			wantErr: jwt.ErrTokenUsedBeforeIssued,
+		},
+		{
+			name: "future_iat_within_leeway",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithRejectFutureIssuedAt[MyClaims](),
+				WithLeeway[MyClaims](5 * time.Second),
+			},
+			token: issue(3 * time.Second),
+		},
+		{
+			name:  "within_max_age",
+			opts:  []Option[MyClaims, *MyClaims]{WithMaxAge[MyClaims](5 * time.Minute)},
+			token: issue(-4 * time.Minute),
+		},
+		{
+			name:    "exceed_max_age",
+			opts:    []Option[MyClaims, *MyClaims]{WithMaxAge[MyClaims](5 * time.Minute)},
+			token:   issue(-6 * time.Minute),
+			wantErr: ErrTokenTooOld,
+		},
+		{
+			name: "exceed_max_age_within_leeway",
+			opts: []Option[MyClaims, *MyClaims]{
+				WithMaxAge[MyClaims](5 * time.Minute),
+				WithLeeway[MyClaims](time.Minute),
+			},
+			token: issue(-5*time.Minute - 30*time.Second),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option[MyClaims, *MyClaims]{
+				WithTimeFunc[MyClaims](func() time.Time { return nowTime }),
+			}, tt.opts...)
+			m := NewTokenManager[MyClaims](encryptionKey, defaultExpire, opts...)
+			got, err := m.VerifyToken(tt.token)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), got.Uid)
+		})
+	}
+}
+
+func TestTokenManager_VerifyToken_MaxAgeWithoutIssuedAt(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, MyClaims{Uid: 1}).
+		SignedString([]byte(encryptionKey))
+	require.NoError(t, err)
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithMaxAge[MyClaims](time.Minute))
+	_, err = m.VerifyToken(token)
+	var cerr *ClaimError
+	require.True(t, errors.As(err, &cerr))
+	assert.Equal(t, "iat", cerr.Claim)
+	assert.ErrorIs(t, err, ErrClaimMissing)
+}
@@ -0,0 +1,200 @@
+package jwtcore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// strictEncoding 是校验时使用的 base64url 编码, 拒绝非规范的编码.
+var strictEncoding = base64.RawURLEncoding.Strict()
+
+// maxPooledBuffer 是 bufferPool 中缓冲区的最大容量.
+const maxPooledBuffer = 64 << 10
+
+// bufferPool 复用解码 token 的缓冲区.
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// verify 校验 token 并将 claims 解码到 clm.
+// 先校验签名再解码 claims, 解码时复用缓冲区, 并缓存解析后的解密密钥.
+// 设置了 jwt.ParserOption 时使用 jwt 解析器, 保证选项的行为与 jwt.ParseWithClaims 一致.
+// 返回的错误与 jwt 解析器的错误格式相同. info 不为 nil 时记录 token 的头部信息.
+func (t *TokenManager[T, PT]) verify(token string, clm PT, info *tokenInfo) error {
+	if len(t.parserOptions) > 0 {
+		_, err := t.parseClaims(token, clm, info)
+		return err
+	}
+	if err := t.checkLimits(token); err != nil {
+		return fmt.Errorf("验证失败: %w", err)
+	}
+	if err := t.verifySignature(token, clm, info); err != nil {
+		return fmt.Errorf("验证失败: %w", err)
+	}
+	return nil
+}
+
+// verifySignature 解析头部并校验签名, 然后解码并校验 claims.
+func (t *TokenManager[T, PT]) verifySignature(token string, clm PT, info *tokenInfo) error {
+	first := strings.IndexByte(token, '.')
+	last := strings.LastIndexByte(token, '.')
+	if first < 0 || first == last || strings.IndexByte(token[first+1:last], '.') >= 0 {
+		return jwtError("token contains an invalid number of segments", jwt.ErrTokenMalformed)
+	}
+
+	bp := bufferPool.Get().(*[]byte)
+	defer putBuffer(bp)
+
+	b, err := decodeInto(bp, token[:first])
+	if err != nil {
+		return jwtError("could not base64 decode header", jwt.ErrTokenMalformed, err)
+	}
+	var header map[string]any
+	if err = json.Unmarshal(b, &header); err != nil {
+		return jwtError("could not JSON decode header", jwt.ErrTokenMalformed, err)
+	}
+	info.setHeader(header)
+	alg, ok := header["alg"].(string)
+	if !ok {
+		return jwtError("signing method (alg) is unspecified", jwt.ErrTokenUnverifiable)
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return jwtError("signing method (alg) is unavailable", jwt.ErrTokenUnverifiable)
+	}
+
+	sig, err := decodeInto(bp, token[last+1:])
+	if err != nil {
+		return jwtError("could not base64 decode signature", jwt.ErrTokenMalformed, err)
+	}
+	key, err := t.signatureKey(method, header)
+	if err != nil {
+		return jwtError("error while executing keyfunc", jwt.ErrTokenUnverifiable, err)
+	}
+	if err = method.Verify(token[:last], sig, key); err != nil {
+		return jwtError("", jwt.ErrTokenSignatureInvalid, err)
+	}
+
+	payload, err := decodeInto(bp, token[first+1:last])
+	if err != nil {
+		return jwtError("could not base64 decode claim", jwt.ErrTokenMalformed, err)
+	}
+	if err = json.Unmarshal(payload, clm); err != nil {
+		return jwtError("could not JSON decode claim", jwt.ErrTokenMalformed, err)
+	}
+	validator := t.validator
+	if validator == nil {
+		validator = jwt.NewValidator(t.verifyParserOptions()...)
+	}
+	if err = validator.Validate(clm); err != nil {
+		return jwtError("", jwt.ErrTokenInvalidClaims, err)
+	}
+	return t.validateClaims(clm, func() ([]byte, error) {
+		return payload, nil
+	})
+}
+
+// tokenInfo 记录校验时解析到的 token 头部信息.
+type tokenInfo struct {
+	alg string
+	kid string
+}
+
+// setHeader 记录头部中的 alg 与 kid, info 为 nil 时不记录.
+func (info *tokenInfo) setHeader(header map[string]any) {
+	if info == nil {
+		return
+	}
+	info.alg, _ = header["alg"].(string)
+	info.kid, _ = header["kid"].(string)
+}
+
+// signatureKey 检查签名算法与 typ, 并返回校验签名的密钥.
+func (t *TokenManager[T, PT]) signatureKey(method jwt.SigningMethod, header map[string]any) (any, error) {
+	if err := t.checkAlgorithm(method); err != nil {
+		return nil, err
+	}
+	if err := t.checkType(header["typ"]); err != nil {
+		return nil, err
+	}
+	if t.x509Roots != nil {
+		return t.verifyKey(&jwt.Token{Header: header, Method: method})
+	}
+	return t.decryptKey(method)
+}
+
+// verifyKeyID 是 verifyKeys 与 signingKeys 的键.
+type verifyKeyID struct {
+	alg string
+	key string
+}
+
+// decryptKey 返回解析后的 DecryptKey, 解析成功的结果会被缓存.
+func (t *TokenManager[T, PT]) decryptKey(method jwt.SigningMethod) (any, error) {
+	if t.verifyKeys == nil {
+		return parseVerifyKey(method, t.DecryptKey)
+	}
+	id := verifyKeyID{alg: method.Alg(), key: t.DecryptKey}
+	if key, ok := t.verifyKeys.Load(id); ok {
+		return key, nil
+	}
+	key, err := parseVerifyKey(method, t.DecryptKey)
+	if err != nil {
+		return nil, err
+	}
+	t.verifyKeys.Store(id, key)
+	return key, nil
+}
+
+// putBuffer 将缓冲区放回 bufferPool, 过大的缓冲区不会被复用.
+func putBuffer(bp *[]byte) {
+	if cap(*bp) <= maxPooledBuffer {
+		bufferPool.Put(bp)
+	}
+}
+
+// decodeInto 将 base64url 编码的 seg 解码到 *bp 中, 返回的切片在下一次调用前有效.
+// 先将 seg 复制到缓冲区的前半部分, 避免将 string 转换为 []byte 时的内存分配.
+func decodeInto(bp *[]byte, seg string) ([]byte, error) {
+	n := len(seg) + strictEncoding.DecodedLen(len(seg))
+	if cap(*bp) < n {
+		*bp = make([]byte, 0, n)
+	}
+	buf := append((*bp)[:0], seg...)
+	dst := (*bp)[len(seg):n]
+	m, err := strictEncoding.Decode(dst, buf)
+	if err != nil {
+		return nil, err
+	}
+	return dst[:m], nil
+}
+
+// decodePayload 返回 token 解码后的 claims JSON.
+func decodePayload(token string) ([]byte, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, jwt.ErrTokenMalformed
+	}
+	return base64.RawURLEncoding.DecodeString(segments[1])
+}
+
+// jwtError 返回与 jwt 解析器格式相同的错误.
+func jwtError(message string, err error, more ...error) error {
+	format, args := "%w", []any{err}
+	if message != "" {
+		format, args = "%w: %s", []any{err, message}
+	}
+	for _, e := range more {
+		format += ": %w"
+		args = append(args, e)
+	}
+	return fmt.Errorf(format, args...)
+}
@@ -0,0 +1,157 @@
+package jwtcore
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTokenManager_verify 检查快速路径与 jwt 解析器的结果一致.
+func TestTokenManager_verify(t *testing.T) {
+	now := func() time.Time { return nowTime }
+	m := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithTimeFunc[MyClaims](now),
+		WithExpectedType[MyClaims]("JWT"),
+		WithRequiredClaims[MyClaims]("uid"),
+	)
+	valid, err := m.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	expired, err := m.WithOptions(WithTimeFunc[MyClaims](func() time.Time {
+		return nowTime.Add(-time.Hour)
+	})).GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+	noUID, err := m.GenerateToken(MyClaims{})
+	require.NoError(t, err)
+	payload := `{"uid":1,"exp":1695571800}`
+	hs512 := NewTokenManager[MyClaims](encryptionKey, defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodHS512),
+		WithTimeFunc[MyClaims](now),
+	)
+	wrongAlg, err := hs512.GenerateToken(MyClaims{Uid: 1})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "valid", token: valid},
+		{name: "expired", token: expired},
+		{name: "missing_required", token: noUID},
+		{name: "wrong_alg", token: wrongAlg},
+		{name: "wrong_typ", token: signRaw(t, `{"alg":"HS256","typ":"at+jwt"}`, payload)},
+		{name: "bad_signature", token: valid[:len(valid)-2] + "AA"},
+		{name: "segments", token: "a.b"},
+		{name: "too_many_segments", token: valid + ".a"},
+		{name: "header_base64", token: "!" + valid[1:]},
+		{name: "header_json", token: signRaw(t, `{`, payload)},
+		{name: "alg_unspecified", token: signRaw(t, `{"typ":"JWT"}`, payload)},
+		{name: "alg_unavailable", token: signRaw(t, `{"alg":"XX256","typ":"JWT"}`, payload)},
+		{name: "none", token: signRaw(t, `{"alg":"none","typ":"JWT"}`, payload)},
+		{name: "signature_base64", token: valid + "!"},
+		{name: "claims_json", token: signRaw(t, `{"alg":"HS256","typ":"JWT"}`, `{"uid":"1"}`)},
+		{name: "empty", token: ""},
+	}
+	// 设置 jwt.ParserOption 后使用 jwt 解析器.
+	parser := m.WithOptions(WithAddParserOption[MyClaims](jwt.WithLeeway(0)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantErr := parser.VerifyToken(tt.token)
+			got, err := m.VerifyToken(tt.token)
+			assert.Equal(t, want, got)
+			if wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			// encoding/json 的错误信息中的类型名称不同, 只比较之前的部分.
+			cut := func(err error) string {
+				msg, _, _ := strings.Cut(err.Error(), "json: ")
+				return msg
+			}
+			assert.Equal(t, cut(wantErr), cut(err))
+			for _, target := range []error{
+				jwt.ErrTokenMalformed, jwt.ErrTokenUnverifiable, jwt.ErrTokenSignatureInvalid,
+				jwt.ErrTokenInvalidClaims, jwt.ErrTokenExpired, ErrAlgorithmNotAllowed,
+				ErrTypeMismatch, ErrClaimMissing,
+			} {
+				assert.Equal(t, errors.Is(wantErr, target), errors.Is(err, target), target)
+			}
+		})
+	}
+}
+
+func TestTokenManager_decryptKey(t *testing.T) {
+	m := NewTokenManager[MyClaims](ecPrivateKeyPEM, defaultExpire,
+		WithMethod[MyClaims](jwt.SigningMethodES256),
+		WithDecryptKey[MyClaims](ecPublicKeyPEM),
+	)
+	key, err := m.decryptKey(jwt.SigningMethodES256)
+	require.NoError(t, err)
+	cached, err := m.decryptKey(jwt.SigningMethodES256)
+	require.NoError(t, err)
+	assert.Same(t, key, cached)
+
+	// 修改 DecryptKey 后重新解析
+	m.DecryptKey = rsaPublicKeyPEM
+	_, err = m.decryptKey(jwt.SigningMethodES256)
+	assert.ErrorIs(t, err, jwt.ErrNotECPublicKey)
+	key, err = m.decryptKey(jwt.SigningMethodRS256)
+	require.NoError(t, err)
+	assert.Equal(t, &rsaPrivateKey.PublicKey, key)
+}
+
+func TestDecodeInto(t *testing.T) {
+	bp := new([]byte)
+	for _, s := range []string{"", "a", "hello world", strings.Repeat("x", 4096)} {
+		b, err := decodeInto(bp, base64.RawURLEncoding.EncodeToString([]byte(s)))
+		require.NoError(t, err)
+		assert.Equal(t, s, string(b))
+	}
+	_, err := decodeInto(bp, "YQ=")
+	assert.Error(t, err)
+	// 非规范的编码
+	_, err = decodeInto(bp, "YR")
+	assert.Error(t, err)
+}
+
+func BenchmarkTokenManager_VerifyToken(b *testing.B) {
+	benchmarks := []struct {
+		name       string
+		method     jwt.SigningMethod
+		privateKey string
+		publicKey  string
+	}{
+		{name: "HS256", method: jwt.SigningMethodHS256, privateKey: encryptionKey, publicKey: encryptionKey},
+		{name: "RS256", method: jwt.SigningMethodRS256, privateKey: rsaPrivateKeyPEM, publicKey: rsaPublicKeyPEM},
+		{name: "ES256", method: jwt.SigningMethodES256, privateKey: ecPrivateKeyPEM, publicKey: ecPublicKeyPEM},
+		{name: "EdDSA", method: jwt.SigningMethodEdDSA, privateKey: edPrivateKeyPEM, publicKey: edPublicKeyPEM},
+	}
+	for _, bm := range benchmarks {
+		m := NewTokenManager[MyClaims](bm.privateKey, time.Hour,
+			WithMethod[MyClaims](bm.method),
+			WithDecryptKey[MyClaims](bm.publicKey),
+		)
+		token, err := m.GenerateToken(MyClaims{Uid: 1})
+		require.NoError(b, err)
+		// 设置 jwt.ParserOption 后使用 jwt 解析器, 作为对比.
+		parser := m.WithOptions(WithAddParserOption[MyClaims](jwt.WithLeeway(0)))
+		for _, v := range []struct {
+			name    string
+			manager *TokenManager[MyClaims, *MyClaims]
+		}{{name: "fast", manager: m}, {name: "parser", manager: parser}} {
+			b.Run(bm.name+"/"+v.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := v.manager.VerifyToken(token); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package tokenotel 使用 OpenTelemetry 实现 token.Tracer.
+package tokenotel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/udugong/token"
+)
+
+// ScopeName 是创建 trace.Tracer 使用的 instrumentation scope 名称.
+const ScopeName = "github.com/udugong/token"
+
+// Tracer 使用 OpenTelemetry 的 trace.Tracer 创建 span.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer 使用 tp 创建 Tracer, tp 为 nil 时使用全局的 TracerProvider.
+func NewTracer(tp trace.TracerProvider, opts ...trace.TracerOption) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: tp.Tracer(ScopeName, opts...)}
+}
+
+// Start 创建 SpanKindInternal 的 span, 是 ctx 中 span 的子 span.
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, token.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+	return ctx, span{s}
+}
+
+// span 将 token.Span 的调用转换为 trace.Span.
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...token.Attribute) {
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, attr := range attrs {
+		kvs[i] = attribute.String(attr.Key, attr.Value)
+	}
+	s.span.SetAttributes(kvs...)
+}
+
+// RecordError 记录错误事件, 并将 span 的状态设置为 codes.Error.
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
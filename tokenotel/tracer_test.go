@@ -0,0 +1,99 @@
+package tokenotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/udugong/token"
+	"github.com/udugong/token/jwtcore"
+)
+
+type MyClaims struct {
+	Uid int64 `json:"uid,omitempty"`
+	jwtcore.RegisteredClaims
+}
+
+func newRecorder() (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func TestTracer(t *testing.T) {
+	recorder, tp := newRecorder()
+	tracer := NewTracer(tp)
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+
+	_, s := tracer.Start(ctx, token.SpanVerifyToken)
+	s.SetAttributes(token.Attribute{Key: token.AttrAlgorithm, Value: "HS256"})
+	errBoom := errors.New("boom")
+	s.RecordError(errBoom)
+	s.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	got := spans[0]
+	assert.Equal(t, token.SpanVerifyToken, got.Name())
+	assert.Equal(t, trace.SpanKindInternal, got.SpanKind())
+	assert.Equal(t, ScopeName, got.InstrumentationScope().Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), got.Parent().SpanID())
+	assert.Equal(t, []attribute.KeyValue{attribute.String(token.AttrAlgorithm, "HS256")}, got.Attributes())
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "boom"}, got.Status())
+	require.Len(t, got.Events(), 1)
+	assert.Equal(t, "exception", got.Events()[0].Name)
+}
+
+func TestTracer_TokenManager(t *testing.T) {
+	recorder, tp := newRecorder()
+	m := jwtcore.NewTokenManager[MyClaims]("key", time.Hour,
+		jwtcore.WithIssuer[MyClaims]("https://issuer.example.com"),
+		jwtcore.WithKeyID[MyClaims]("k1"),
+		jwtcore.WithTracer[MyClaims](NewTracer(tp)),
+	)
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+	tokenString, err := m.GenerateTokenContext(ctx, MyClaims{Uid: 1})
+	require.NoError(t, err)
+	_, err = m.VerifyTokenContext(ctx, tokenString)
+	require.NoError(t, err)
+	_, err = m.VerifyTokenContext(ctx, tokenString+"x")
+	require.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for _, s := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), s.Parent().SpanID())
+	}
+	assert.Equal(t, token.SpanGenerateToken, spans[0].Name())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String(token.AttrAlgorithm, "HS256"),
+		attribute.String(token.AttrKeyID, "k1"),
+		attribute.String(token.AttrIssuer, "https://issuer.example.com"),
+		attribute.String(token.AttrOutcome, token.OutcomeSuccess),
+	}, spans[0].Attributes())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+
+	failed := spans[2]
+	assert.Equal(t, token.SpanVerifyToken, failed.Name())
+	assert.Contains(t, failed.Attributes(), attribute.String(token.AttrOutcome, token.OutcomeFailure))
+	assert.Contains(t, failed.Attributes(), attribute.String(token.AttrErrorClass, string(jwtcore.CategorySignature)))
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, err.Error(), failed.Status().Description)
+}
+
+func TestNewTracer_Global(t *testing.T) {
+	ctx, s := NewTracer(nil).Start(context.Background(), token.SpanGenerateToken)
+	s.End()
+	// 全局的 TracerProvider 默认不记录 span
+	assert.False(t, trace.SpanFromContext(ctx).IsRecording())
+}
@@ -0,0 +1,200 @@
+// Package tokentest 提供 token.Manager 实现的一致性测试与记录 span 的 token.Tracer.
+package tokentest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/udugong/token"
+)
+
+// Fixture 是一致性测试使用的 token 管理器及其配置.
+type Fixture[T any] struct {
+	// Manager 被测试的 token 管理器.
+	Manager token.Manager[T]
+
+	// Claims 返回第 i 个用于测试的 claims, 不同的 i 应该返回不同的 claims.
+	Claims func(i int) T
+
+	// Equal 比较生成 token 时使用的 claims 与校验 token 得到的 claims.
+	// 校验得到的 claims 可能包含 token 管理器添加的字段 (例如 exp), 应该只比较业务字段.
+	Equal func(want, got T) bool
+
+	// Advance 将 Manager 使用的时钟拨快 d.
+	Advance func(d time.Duration)
+
+	// TTL token 的有效期.
+	TTL time.Duration
+
+	// ErrExpired, ErrSignature 与 ErrMalformed 分别为 token 过期、签名无效与格式错误时
+	// 错误链中应该包含的错误, 为 nil 时只检查返回了错误.
+	ErrExpired   error
+	ErrSignature error
+	ErrMalformed error
+}
+
+// Factory 为每个子测试创建新的 Fixture, 子测试之间不共享时钟.
+type Factory[T any] func(t *testing.T) Fixture[T]
+
+// RunManagerConformance 检查 token 管理器是否符合 token.Manager 的约定:
+// 生成的 token 可以通过校验并得到相同的 claims; 篡改 token 的任意部分都会导致校验失败;
+// token 在有效期后校验失败; 错误链中包含对应类别的错误; 可以安全地在多个 goroutine 中使用;
+// 校验失败时返回零值的 claims.
+func RunManagerConformance[T any](t *testing.T, factory Factory[T]) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		f := factory(t)
+		for i := 0; i < 3; i++ {
+			want := f.Claims(i)
+			tk := generate(t, f, want)
+			got, err := f.Manager.VerifyToken(tk)
+			if err != nil {
+				t.Fatalf("VerifyToken(%q) 返回错误: %v", tk, err)
+			}
+			if !f.Equal(want, got) {
+				t.Errorf("VerifyToken 返回 %+v, 期望 %+v", got, want)
+			}
+		}
+	})
+
+	t.Run("Tamper", func(t *testing.T) {
+		f := factory(t)
+		tk := generate(t, f, f.Claims(0))
+		segments := strings.Split(tk, ".")
+		for i := range segments {
+			for _, pos := range []int{0, len(segments[i]) / 2} {
+				tampered := tamper(segments, i, pos)
+				if tampered == tk {
+					continue
+				}
+				assertFails(t, f, tampered, nil)
+			}
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		f := factory(t)
+		tk := generate(t, f, f.Claims(0))
+		for _, bad := range []string{
+			"",
+			".",
+			"..",
+			"not a token",
+			tk[:len(tk)/2],
+			tk + ".",
+			strings.Replace(tk, ".", "", 1),
+		} {
+			assertFails(t, f, bad, f.ErrMalformed)
+		}
+	})
+
+	t.Run("WrongSignature", func(t *testing.T) {
+		f := factory(t)
+		tk := generate(t, f, f.Claims(0))
+		other := generate(t, f, f.Claims(1))
+		dot := strings.LastIndexByte(tk, '.')
+		otherDot := strings.LastIndexByte(other, '.')
+		if dot < 0 || otherDot < 0 {
+			t.Skip("token 不包含签名")
+		}
+		// 使用另一个 token 的签名.
+		assertFails(t, f, tk[:dot]+other[otherDot:], f.ErrSignature)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		f := factory(t)
+		want := f.Claims(0)
+		tk := generate(t, f, want)
+		f.Advance(f.TTL / 2)
+		got, err := f.Manager.VerifyToken(tk)
+		if err != nil {
+			t.Fatalf("有效期内 VerifyToken 返回错误: %v", err)
+		}
+		if !f.Equal(want, got) {
+			t.Errorf("VerifyToken 返回 %+v, 期望 %+v", got, want)
+		}
+		f.Advance(f.TTL/2 + time.Minute)
+		assertFails(t, f, tk, f.ErrExpired)
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		f := factory(t)
+		const workers, rounds = 8, 20
+		var wg sync.WaitGroup
+		errs := make(chan string, workers*rounds)
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for r := 0; r < rounds; r++ {
+					want := f.Claims(w*rounds + r)
+					tk, err := f.Manager.GenerateToken(want)
+					if err != nil {
+						errs <- "GenerateToken 返回错误: " + err.Error()
+						continue
+					}
+					got, err := f.Manager.VerifyToken(tk)
+					if err != nil {
+						errs <- "VerifyToken 返回错误: " + err.Error()
+						continue
+					}
+					if !f.Equal(want, got) {
+						errs <- "VerifyToken 返回了其他 token 的 claims"
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+		close(errs)
+		for msg := range errs {
+			t.Error(msg)
+		}
+	})
+}
+
+func generate[T any](t *testing.T, f Fixture[T], clm T) string {
+	t.Helper()
+	tk, err := f.Manager.GenerateToken(clm)
+	if err != nil {
+		t.Fatalf("GenerateToken 返回错误: %v", err)
+	}
+	return tk
+}
+
+// assertFails 断言 token 校验失败并返回零值的 claims, target 不为 nil 时检查错误链.
+func assertFails[T any](t *testing.T, f Fixture[T], tk string, target error) {
+	t.Helper()
+	clm, err := f.Manager.VerifyToken(tk)
+	if err == nil {
+		t.Errorf("VerifyToken(%q) 校验通过, 期望失败", tk)
+		return
+	}
+	if target != nil && !errors.Is(err, target) {
+		t.Errorf("VerifyToken(%q) 返回 %q, 期望包含 %q", tk, err, target)
+	}
+	var zero T
+	if !reflect.DeepEqual(clm, zero) {
+		t.Errorf("VerifyToken(%q) 失败时返回了非零值的 claims: %+v", tk, clm)
+	}
+}
+
+// tamper 替换第 i 段 pos 位置的字符.
+// 避免修改段的最后一个字符, 因为 base64 末尾的填充位被修改后可能解码出相同的内容.
+func tamper(segments []string, i, pos int) string {
+	seg := segments[i]
+	if seg == "" || (pos == len(seg)-1 && pos > 0) {
+		return strings.Join(segments, ".")
+	}
+	b := []byte(seg)
+	if b[pos] == 'A' {
+		b[pos] = 'B'
+	} else {
+		b[pos] = 'A'
+	}
+	out := append([]string(nil), segments...)
+	out[i] = string(b)
+	return strings.Join(out, ".")
+}
@@ -0,0 +1,72 @@
+package tokentest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token/jwtcore"
+	"github.com/udugong/token/jwtcore/jwtcoretest"
+)
+
+type MyClaims struct {
+	Uid int64 `json:"uid,omitempty"`
+	jwtcore.RegisteredClaims
+}
+
+func jwtcoreFactory(opts ...jwtcore.Option[MyClaims, *MyClaims]) Factory[MyClaims] {
+	return func(t *testing.T) Fixture[MyClaims] {
+		clock := jwtcoretest.NewFakeClock(time.Now())
+		opts := append([]jwtcore.Option[MyClaims, *MyClaims]{
+			jwtcore.WithTimeFunc[MyClaims](clock.Now),
+		}, opts...)
+		return Fixture[MyClaims]{
+			Manager: jwtcore.NewTokenManager[MyClaims]("sign key", 10*time.Minute, opts...),
+			Claims:  func(i int) MyClaims { return MyClaims{Uid: int64(i + 1)} },
+			Equal:   func(want, got MyClaims) bool { return want.Uid == got.Uid },
+			Advance: clock.Advance,
+			TTL:     10 * time.Minute,
+
+			ErrExpired:   jwt.ErrTokenExpired,
+			ErrSignature: jwt.ErrTokenSignatureInvalid,
+			ErrMalformed: jwt.ErrTokenMalformed,
+		}
+	}
+}
+
+func TestRunManagerConformance(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	tests := []struct {
+		name    string
+		factory Factory[MyClaims]
+	}{
+		{
+			name:    "hs256",
+			factory: jwtcoreFactory(),
+		},
+		{
+			name: "es256",
+			factory: jwtcoreFactory(
+				jwtcore.WithSigner[MyClaims](jwtcore.NewKeySigner(jwt.SigningMethodES256, key, "")),
+				jwtcore.WithDecryptKey[MyClaims](publicKey),
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RunManagerConformance(t, tt.factory)
+		})
+	}
+}
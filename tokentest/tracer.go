@@ -0,0 +1,100 @@
+package tokentest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/udugong/token"
+)
+
+// SpanRecorder 是在内存中记录 span 的 token.Tracer, 用于测试追踪.
+// SpanRecorder 可以安全地在多个 goroutine 中使用.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+// RecordedSpan 是 SpanRecorder 记录的 span.
+type RecordedSpan struct {
+	ID         int // 从 1 开始, 按创建的顺序递增
+	ParentID   int // 父 span 的 ID, 没有父 span 时为 0
+	Name       string
+	Attributes map[string]string
+	Errors     []error
+	Ended      bool
+}
+
+// recordedSpan 实现 token.Span.
+type recordedSpan struct {
+	recorder *SpanRecorder
+	span     RecordedSpan
+}
+
+type spanContextKey struct{}
+
+// NewSpanRecorder 创建 SpanRecorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start 创建 span, ctx 中有 SpanRecorder 创建的 span 时, 新的 span 是它的子 span.
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, token.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &recordedSpan{
+		recorder: r,
+		span: RecordedSpan{
+			ID:         len(r.spans) + 1,
+			Name:       name,
+			Attributes: make(map[string]string),
+		},
+	}
+	if parent, ok := ctx.Value(spanContextKey{}).(*recordedSpan); ok && parent.recorder == r {
+		s.span.ParentID = parent.span.ID
+	}
+	r.spans = append(r.spans, s)
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// Spans 按创建的顺序返回所有 span 的副本.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]RecordedSpan, len(r.spans))
+	for i, s := range r.spans {
+		spans[i] = s.span
+		spans[i].Attributes = make(map[string]string, len(s.span.Attributes))
+		for k, v := range s.span.Attributes {
+			spans[i].Attributes[k] = v
+		}
+		spans[i].Errors = append([]error(nil), s.span.Errors...)
+	}
+	return spans
+}
+
+// Reset 删除所有 span.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...token.Attribute) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	for _, attr := range attrs {
+		s.span.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+func (s *recordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.span.Ended = true
+}
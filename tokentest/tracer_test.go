@@ -0,0 +1,49 @@
+package tokentest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/udugong/token"
+)
+
+func TestSpanRecorder(t *testing.T) {
+	r := NewSpanRecorder()
+	ctx, parent := r.Start(context.Background(), "parent")
+	_, child := r.Start(ctx, "child")
+	child.SetAttributes(token.Attribute{Key: token.AttrOutcome, Value: token.OutcomeFailure})
+	errBoom := errors.New("boom")
+	child.RecordError(errBoom)
+	child.End()
+	// 其他 SpanRecorder 创建的 span 不是父 span
+	_, other := NewSpanRecorder().Start(ctx, "other")
+	_, orphan := r.Start(context.Background(), "orphan")
+
+	spans := r.Spans()
+	require.Len(t, spans, 3)
+	assert.Equal(t, RecordedSpan{ID: 1, Name: "parent", Attributes: map[string]string{}}, spans[0])
+	assert.Equal(t, RecordedSpan{
+		ID:         2,
+		ParentID:   1,
+		Name:       "child",
+		Attributes: map[string]string{token.AttrOutcome: token.OutcomeFailure},
+		Errors:     []error{errBoom},
+		Ended:      true,
+	}, spans[1])
+	assert.Equal(t, 0, spans[2].ParentID)
+
+	// Spans 返回副本
+	spans[1].Attributes["k"] = "v"
+	parent.End()
+	assert.NotContains(t, r.Spans()[1].Attributes, "k")
+	assert.True(t, r.Spans()[0].Ended)
+	other.End()
+	orphan.End()
+
+	r.Reset()
+	assert.Empty(t, r.Spans())
+}
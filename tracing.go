@@ -0,0 +1,53 @@
+package token
+
+import "context"
+
+// ContextManager 是使用 context 的 token 管理接口.
+// 设置了 Tracer 时, 签发与校验的 span 是 ctx 中 span 的子 span.
+type ContextManager[T any] interface {
+	Manager[T]
+	GenerateTokenContext(ctx context.Context, clm T) (string, error)
+	VerifyTokenContext(ctx context.Context, token string) (T, error)
+}
+
+// Tracer 创建追踪 token 操作的 span.
+// OpenTelemetry 的适配器在 github.com/udugong/token/tokenotel 中, 避免引入不需要的依赖.
+type Tracer interface {
+	// Start 创建名为 name 的 span, 返回的 ctx 包含该 span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span 是一次 token 操作.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	// RecordError 记录操作失败的原因.
+	RecordError(err error)
+	End()
+}
+
+// Attribute 是 span 的属性.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// span 的名称.
+const (
+	SpanGenerateToken = "token.GenerateToken"
+	SpanVerifyToken   = "token.VerifyToken"
+)
+
+// span 的属性.
+const (
+	AttrAlgorithm  = "token.algorithm"   // 签名算法
+	AttrKeyID      = "token.kid"         // 密钥 ID
+	AttrIssuer     = "token.issuer"      // 签发人
+	AttrOutcome    = "token.outcome"     // OutcomeSuccess 或 OutcomeFailure
+	AttrErrorClass = "token.error_class" // 失败的类别
+)
+
+// AttrOutcome 的值.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)